@@ -0,0 +1,489 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/fatih/color"
+	"github.com/urfave/cli"
+)
+
+// serviceABI is the part of a service contract the admin commands use. The
+// broker only accepts register calls from contracts, so a service registers
+// through its own register function like the example contracts have.
+const serviceABI = `[{"inputs":[{"internalType":"bool","name":"_ordered","type":"bool"}],"name":"register","outputs":[],"stateMutability":"nonpayable","type":"function"}]`
+
+// adminBroker is the admin workflow both broker flavors share
+type adminBroker interface {
+	Audit(opts *bind.TransactOpts, addr common.Address, status int64) (*types.Transaction, error)
+	Initialize(opts *bind.TransactOpts) (*types.Transaction, error)
+	SetAdmins(opts *bind.TransactOpts, _admins []common.Address, _adminThreshold uint64) (*types.Transaction, error)
+	Admins(opts *bind.CallOpts, arg0 *big.Int) (common.Address, error)
+	AdminThreshold(opts *bind.CallOpts) (uint64, error)
+	GetLocalWhiteList(opts *bind.CallOpts, addr common.Address) (bool, error)
+	GetLocalServiceList(opts *bind.CallOpts) ([]string, error)
+}
+
+var (
+	_ adminBroker = (*Broker)(nil)
+	_ adminBroker = (*BrokerDirect)(nil)
+)
+
+var (
+	modeFlag = cli.StringFlag{
+		Name:  "mode",
+		Usage: "Specify the broker mode, relay or direct, the mode of the plugin config by default",
+	}
+	serviceFlag = cli.StringFlag{
+		Name:     "service",
+		Usage:    "Specify the address of the service contract",
+		Required: true,
+	}
+	chainIDFlag = cli.StringFlag{
+		Name:     "chain-id",
+		Usage:    "Specify the id of the counterparty appchain",
+		Required: true,
+	}
+)
+
+// bindAdmin binds the broker of the mode flag, or of the configured mode
+func (b *brokerCLI) bindAdmin(ctx *cli.Context) (adminBroker, error) {
+	mode := ctx.String(modeFlag.Name)
+	if mode == "" {
+		mode = b.cfg.Ether.Mode
+	}
+	switch mode {
+	case relayMode:
+		return b.broker, nil
+	case directMode:
+		return b.directBroker()
+	default:
+		return nil, fmt.Errorf("unknown broker mode %q", mode)
+	}
+}
+
+func (b *brokerCLI) directBroker() (*BrokerDirect, error) {
+	broker, err := NewBrokerDirect(b.address, b.ethClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate a BrokerDirect contract: %w", err)
+	}
+	return broker, nil
+}
+
+func serviceAddress(ctx *cli.Context) (common.Address, error) {
+	service := ctx.String(serviceFlag.Name)
+	if !common.IsHexAddress(service) {
+		return common.Address{}, fmt.Errorf("invalid service address %q", service)
+	}
+	return common.HexToAddress(service), nil
+}
+
+// loadAdmins reads the admins of the broker. The admins array has no length
+// getter, it is read until the index is out of range.
+func loadAdmins(broker adminBroker) ([]common.Address, uint64, error) {
+	threshold, err := broker.AdminThreshold(nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get admin threshold: %w", err)
+	}
+	var admins []common.Address
+	for i := int64(0); ; i++ {
+		admin, err := broker.Admins(nil, big.NewInt(i))
+		if isCallReverted(err) {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("get admin %d: %w", i, err)
+		}
+		admins = append(admins, admin)
+	}
+	return admins, threshold, nil
+}
+
+func printServiceStatus(broker adminBroker, service common.Address) error {
+	registered, err := broker.GetLocalWhiteList(nil, service)
+	if err != nil {
+		return err
+	}
+	if registered {
+		fmt.Printf("Service %s is registered\n", service.Hex())
+	} else {
+		fmt.Printf("Service %s is not registered, it waits for the audit of the admins\n", service.Hex())
+	}
+	return nil
+}
+
+func printLocalServices(broker adminBroker) error {
+	services, err := broker.GetLocalServiceList(nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Local services (%d):\n", len(services))
+	for _, service := range services {
+		fmt.Printf("  %s\n", service)
+	}
+	return nil
+}
+
+func printAppchain(broker *BrokerDirect, chainID string) error {
+	brokerAddr, trustRoot, ruleAddr, err := broker.GetAppchainInfo(nil, chainID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Appchain %s:\n  Broker: %s\n  Rule: %s\n  Trust root: %s\n", chainID, brokerAddr, ruleAddr.Hex(), hexutil.Encode(trustRoot))
+	return nil
+}
+
+// remoteServiceID is the key the direct broker keeps the white list of a
+// remote service under
+func remoteServiceID(chainID, serviceID string) string {
+	return fmt.Sprintf(":%s:%s", chainID, serviceID)
+}
+
+func printRemoteService(broker *BrokerDirect, fullID string) error {
+	whiteList, err := broker.GetRSWhiteList(nil, fullID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Remote service %s may call:\n", fullID)
+	for _, addr := range whiteList {
+		fmt.Printf("  %s\n", addr.Hex())
+	}
+	return nil
+}
+
+var adminCMD = cli.Command{
+	Name:  "admin",
+	Usage: "Register, audit and manage the services of the broker",
+	Subcommands: []cli.Command{
+		{
+			Name:  "show",
+			Usage: "Show the admins and services of the broker",
+			Flags: []cli.Flag{configFlag, modeFlag},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					broker, err := b.bindAdmin(ctx)
+					if err != nil {
+						return err
+					}
+					admins, threshold, err := loadAdmins(broker)
+					if err != nil {
+						return err
+					}
+					printValidators("Broker admins", admins, threshold)
+					if err := printLocalServices(broker); err != nil {
+						return err
+					}
+
+					direct, ok := broker.(*BrokerDirect)
+					if !ok {
+						return nil
+					}
+					remotes, err := direct.GetRemoteServiceList(nil)
+					if err != nil {
+						return err
+					}
+					for _, remote := range remotes {
+						if err := printRemoteService(direct, remote); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "register",
+			Usage: "Register a service contract to the broker through its register function",
+			Flags: []cli.Flag{
+				configFlag,
+				modeFlag,
+				keyFlag,
+				passwordFlag,
+				serviceFlag,
+				cli.BoolFlag{
+					Name:  "ordered",
+					Usage: "Specify whether the interchain txs of the service are executed in order",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					broker, err := b.bindAdmin(ctx)
+					if err != nil {
+						return err
+					}
+					service, err := serviceAddress(ctx)
+					if err != nil {
+						return err
+					}
+					parsed, err := abi.JSON(strings.NewReader(serviceABI))
+					if err != nil {
+						return fmt.Errorf("abi unmarshal: %w", err)
+					}
+					contract := bind.NewBoundContract(service, parsed, b.ethClient, b.ethClient, b.ethClient)
+
+					opts, err := b.transactor(ctx)
+					if err != nil {
+						return err
+					}
+					tx, err := contract.Transact(opts, "register", ctx.Bool("ordered"))
+					if err != nil {
+						return err
+					}
+					if _, err := b.wait(tx); err != nil {
+						return err
+					}
+					return printServiceStatus(broker, service)
+				})
+			},
+		},
+		{
+			Name:  "audit",
+			Usage: "Vote for or against the registration of a service",
+			Flags: []cli.Flag{
+				configFlag,
+				modeFlag,
+				keyFlag,
+				passwordFlag,
+				serviceFlag,
+				cli.BoolFlag{
+					Name:  "reject",
+					Usage: "Vote against the registration, it is approved by default",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					broker, err := b.bindAdmin(ctx)
+					if err != nil {
+						return err
+					}
+					service, err := serviceAddress(ctx)
+					if err != nil {
+						return err
+					}
+					status := int64(1)
+					if ctx.Bool("reject") {
+						status = 0
+					}
+
+					opts, err := b.transactor(ctx)
+					if err != nil {
+						return err
+					}
+					tx, err := broker.Audit(opts, service, status)
+					if err != nil {
+						return err
+					}
+					if _, err := b.wait(tx); err != nil {
+						return err
+					}
+					if err := printServiceStatus(broker, service); err != nil {
+						return err
+					}
+					return printLocalServices(broker)
+				})
+			},
+		},
+		{
+			Name:  "initialize",
+			Usage: "Remove all the services and interchain records of the broker",
+			Flags: []cli.Flag{configFlag, modeFlag, keyFlag, passwordFlag},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					broker, err := b.bindAdmin(ctx)
+					if err != nil {
+						return err
+					}
+					opts, err := b.transactor(ctx)
+					if err != nil {
+						return err
+					}
+					tx, err := broker.Initialize(opts)
+					if err != nil {
+						return err
+					}
+					if _, err := b.wait(tx); err != nil {
+						return err
+					}
+					if err := printLocalServices(broker); err != nil {
+						return err
+					}
+					color.Green("Initialized broker successfully")
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "set-admins",
+			Usage: "Replace the admins of the broker",
+			Flags: []cli.Flag{
+				configFlag,
+				modeFlag,
+				keyFlag,
+				passwordFlag,
+				cli.StringFlag{
+					Name:     "admins",
+					Usage:    "Specify the comma separated addresses of the admins",
+					Required: true,
+				},
+				cli.Uint64Flag{
+					Name:     "threshold",
+					Usage:    "Specify how many admins have to approve a service",
+					Required: true,
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					broker, err := b.bindAdmin(ctx)
+					if err != nil {
+						return err
+					}
+					admins, err := parseAddresses(strings.Split(ctx.String("admins"), ","))
+					if err != nil {
+						return err
+					}
+					threshold := ctx.Uint64("threshold")
+					if threshold == 0 || threshold > uint64(len(admins)) {
+						return fmt.Errorf("threshold %d is invalid for %d admins", threshold, len(admins))
+					}
+
+					opts, err := b.transactor(ctx)
+					if err != nil {
+						return err
+					}
+					tx, err := broker.SetAdmins(opts, admins, threshold)
+					if err != nil {
+						return err
+					}
+					if _, err := b.wait(tx); err != nil {
+						return err
+					}
+
+					admins, threshold, err = loadAdmins(broker)
+					if err != nil {
+						return err
+					}
+					printValidators("Broker admins", admins, threshold)
+					color.Green("Set admins successfully")
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "register-appchain",
+			Usage: "Register a counterparty appchain to the direct broker",
+			Flags: []cli.Flag{
+				configFlag,
+				keyFlag,
+				passwordFlag,
+				chainIDFlag,
+				cli.StringFlag{
+					Name:     "broker",
+					Usage:    "Specify the broker of the appchain",
+					Required: true,
+				},
+				cli.StringFlag{
+					Name:     "rule",
+					Usage:    "Specify the address of the validation rule contract",
+					Required: true,
+				},
+				cli.StringFlag{
+					Name:  "trust-root",
+					Usage: "Specify the file keeping the trust root of the appchain",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					broker, err := b.directBroker()
+					if err != nil {
+						return err
+					}
+					rule := ctx.String("rule")
+					if !common.IsHexAddress(rule) {
+						return fmt.Errorf("invalid rule address %q", rule)
+					}
+					var trustRoot []byte
+					if path := ctx.String("trust-root"); path != "" {
+						if trustRoot, err = ioutil.ReadFile(path); err != nil {
+							return err
+						}
+					}
+
+					chainID := ctx.String(chainIDFlag.Name)
+					opts, err := b.transactor(ctx)
+					if err != nil {
+						return err
+					}
+					tx, err := broker.RegisterAppchain(opts, chainID, ctx.String("broker"), common.HexToAddress(rule), trustRoot)
+					if err != nil {
+						return err
+					}
+					if _, err := b.wait(tx); err != nil {
+						return err
+					}
+					if err := printAppchain(broker, chainID); err != nil {
+						return err
+					}
+					color.Green("Registered appchain successfully")
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "register-remote-service",
+			Usage: "Allow a service of a counterparty appchain to call local services through the direct broker",
+			Flags: []cli.Flag{
+				configFlag,
+				keyFlag,
+				passwordFlag,
+				chainIDFlag,
+				cli.StringFlag{
+					Name:     "service-id",
+					Usage:    "Specify the id of the remote service",
+					Required: true,
+				},
+				cli.StringFlag{
+					Name:     "whitelist",
+					Usage:    "Specify the comma separated local services the remote service may call",
+					Required: true,
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					broker, err := b.directBroker()
+					if err != nil {
+						return err
+					}
+					whiteList, err := parseAddresses(strings.Split(ctx.String("whitelist"), ","))
+					if err != nil {
+						return err
+					}
+
+					chainID, serviceID := ctx.String(chainIDFlag.Name), ctx.String("service-id")
+					opts, err := b.transactor(ctx)
+					if err != nil {
+						return err
+					}
+					tx, err := broker.RegisterRemoteService(opts, chainID, serviceID, whiteList)
+					if err != nil {
+						return err
+					}
+					if _, err := b.wait(tx); err != nil {
+						return err
+					}
+					if err := printRemoteService(broker, remoteServiceID(chainID, serviceID)); err != nil {
+						return err
+					}
+					color.Green("Registered remote service successfully")
+					return nil
+				})
+			},
+		},
+	},
+}
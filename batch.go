@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// batchItem is the outcome of a single entry of a batched broker call.
+// Status tells whether the broker applied the entry, so only items with a
+// false status are worth retrying. Reason explains a failure, or the error
+// receipt the broker produced for an applied ibtp. The src and dst of the
+// broker call tell apart entries of different service pairs with the same
+// index.
+type batchItem struct {
+	ID     string `json:"id"`
+	Index  uint64 `json:"index"`
+	Status bool   `json:"status"`
+	Reason string `json:"reason,omitempty"`
+
+	src string
+	dst string
+}
+
+func batchKey(src, dst string, index uint64) string {
+	return fmt.Sprintf("%s-%s#%d", src, dst, index)
+}
+
+func (item *batchItem) key() string {
+	return batchKey(item.src, item.dst, item.Index)
+}
+
+// newInterchainItems creates one pending item per ibtp of an invokeInterchains
+// call, in call order, identified by the source service.
+func newInterchainItems(srcFullID, destAddr []string, index []uint64) []*batchItem {
+	items := make([]*batchItem, len(srcFullID))
+	for i, src := range srcFullID {
+		items[i] = &batchItem{ID: src, Index: index[i], src: src, dst: destAddr[i]}
+	}
+	return items
+}
+
+// newReceiptItems creates one pending item per receipt of an invokeReceipts
+// call, in call order, identified by the destination service.
+func newReceiptItems(srcAddr, dstFullID []string, index []uint64) []*batchItem {
+	items := make([]*batchItem, len(dstFullID))
+	for i, dst := range dstFullID {
+		items[i] = &batchItem{ID: dst, Index: index[i], src: srcAddr[i], dst: dst}
+	}
+	return items
+}
+
+// serviceAddr returns the address part of a full service id
+func serviceAddr(fullID string) string {
+	return fullID[strings.LastIndex(fullID, ":")+1:]
+}
+
+// failBatch marks every item of the batch as failed with the same reason,
+// used when the batch tx itself could not be sent or was reverted.
+func failBatch(items []*batchItem, reason string) {
+	for _, item := range items {
+		item.Status = false
+		item.Reason = reason
+	}
+}
+
+// batchResponse folds the per item results into one response. The batch only
+// succeeds if all items did; the message carries every item as json so the
+// caller can retry the failed ones alone.
+func batchResponse(items []*batchItem) *pb.SubmitIBTPResponse {
+	ret := &pb.SubmitIBTPResponse{Status: true}
+	for _, item := range items {
+		if !item.Status {
+			ret.Status = false
+			break
+		}
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		ret.Message = err.Error()
+		return ret
+	}
+	ret.Message = string(data)
+	return ret
+}
+
+// receiptBatchStatus fills the items from the throwReceiptBatchStatus events
+// of a mined invokeReceipts tx. Items without an event are reported as failed.
+func (b *relayBroker) receiptBatchStatus(receipt *types.Receipt, items []*batchItem) {
+	found := make(map[string]*BrokerThrowReceiptBatchStatus, len(items))
+	for _, log := range receipt.Logs {
+		if len(log.Topics) == 0 || log.Topics[0] != b.abi.Events["throwReceiptBatchStatus"].ID {
+			continue
+		}
+		ev, err := b.session.Contract.ParseThrowReceiptBatchStatus(*log)
+		if err != nil {
+			logger.Warn("Parse throwReceiptBatchStatus failed", "hash", receipt.TxHash.Hex(), "error", err.Error())
+			continue
+		}
+		found[batchKey(ev.SrcAddr, ev.DstFullID, ev.Index)] = ev
+	}
+
+	for _, item := range items {
+		ev, ok := found[item.key()]
+		if !ok {
+			item.Status = false
+			item.Reason = "no receipt status found in batch tx"
+			continue
+		}
+		item.Status = ev.Status
+		item.Reason = ev.Reason
+	}
+}
+
+// interchainBatchStatus fills the items from the throwInterchainBatchStatus
+// events of a mined invokeInterchains tx. Applied ibtps whose receipt is not a
+// success carry the receipt type and error message in their reason.
+func (b *relayBroker) interchainBatchStatus(receipt *types.Receipt, items []*batchItem) {
+	found := make(map[string]*BrokerThrowInterchainBatchStatus, len(items))
+	receipts := make(map[string]*BrokerThrowReceiptEvent, len(items))
+	for _, log := range receipt.Logs {
+		if len(log.Topics) == 0 {
+			continue
+		}
+		switch log.Topics[0] {
+		case b.abi.Events["throwInterchainBatchStatus"].ID:
+			ev, err := b.session.Contract.ParseThrowInterchainBatchStatus(*log)
+			if err != nil {
+				logger.Warn("Parse throwInterchainBatchStatus failed", "hash", receipt.TxHash.Hex(), "error", err.Error())
+				continue
+			}
+			found[batchKey(ev.SrcFullID, ev.DestAddr, ev.Index)] = ev
+		case b.abi.Events["throwReceiptEvent"].ID:
+			ev, err := b.session.Contract.ParseThrowReceiptEvent(*log)
+			if err != nil {
+				logger.Warn("Parse throwReceiptEvent failed", "hash", receipt.TxHash.Hex(), "error", err.Error())
+				continue
+			}
+			// the receipt names the destination by its full service id
+			receipts[batchKey(ev.SrcFullID, serviceAddr(ev.DstFullID), ev.Index)] = ev
+		}
+	}
+
+	for _, item := range items {
+		key := item.key()
+		ev, ok := found[key]
+		if !ok {
+			item.Status = false
+			item.Reason = "no interchain status found in batch tx"
+			continue
+		}
+		item.Status = ev.Status
+		item.Reason = ev.Reason
+		if !ev.Status || item.Reason != "" {
+			continue
+		}
+		if rev, ok := receipts[key]; ok && rev.Typ != uint64(pb.IBTP_RECEIPT_SUCCESS) {
+			item.Reason = receiptReason(rev)
+		}
+	}
+}
+
+// receiptReason describes a non success receipt thrown by the broker, using
+// the error message it stores as the only result when there is one.
+func receiptReason(ev *BrokerThrowReceiptEvent) string {
+	typ := pb.IBTP_Type(ev.Typ).String()
+	if len(ev.Results) == 0 || len(ev.Results[0]) != 1 {
+		return typ
+	}
+	return fmt.Sprintf("%s: %s", typ, ev.Results[0][0])
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// brokerLog packs args as the log of the broker event name
+func brokerLog(t *testing.T, b *relayBroker, name string, args ...interface{}) *types.Log {
+	t.Helper()
+	ev := b.abi.Events[name]
+	data, err := ev.Inputs.NonIndexed().Pack(args...)
+	if err != nil {
+		t.Fatalf("pack %s: %v", name, err)
+	}
+	return &types.Log{Topics: []common.Hash{ev.ID}, Data: data}
+}
+
+func newTestRelayBroker(t *testing.T) *relayBroker {
+	t.Helper()
+	b, err := newRelayBroker(common.HexToAddress("0x01"), nil, &bind.TransactOpts{})
+	if err != nil {
+		t.Fatalf("new broker: %v", err)
+	}
+	return b
+}
+
+func TestReceiptBatchStatusMatchesServicePair(t *testing.T) {
+	b := newTestRelayBroker(t)
+	items := newReceiptItems([]string{"0xa", "0xb", "0xa"}, []string{"1356:chain0:0xc", "1356:chain0:0xc", "1356:chain1:0xd"}, []uint64{1, 1, 1})
+	receipt := &types.Receipt{Logs: []*types.Log{
+		brokerLog(t, b, "throwReceiptBatchStatus", uint64(1), "1356:chain1:0xd", "0xa", false, "invalid multi-signature"),
+		brokerLog(t, b, "throwReceiptBatchStatus", uint64(1), "1356:chain0:0xc", "0xb", true, ""),
+		brokerLog(t, b, "throwReceiptBatchStatus", uint64(1), "1356:chain0:0xc", "0xa", true, ""),
+	}}
+
+	b.receiptBatchStatus(receipt, items)
+	for i, want := range []bool{true, true, false} {
+		if items[i].Status != want {
+			t.Fatalf("item %d status %v, want %v", i, items[i].Status, want)
+		}
+	}
+	if items[2].Reason != "invalid multi-signature" {
+		t.Fatalf("item 2 reason %q", items[2].Reason)
+	}
+}
+
+func TestInterchainBatchStatusMatchesServicePair(t *testing.T) {
+	b := newTestRelayBroker(t)
+	items := newInterchainItems([]string{"1356:chain0:0xa", "1356:chain0:0xa", "1356:chain1:0xb"}, []string{"0xc", "0xd", "0xc"}, []uint64{3, 3, 3})
+	receipt := &types.Receipt{Logs: []*types.Log{
+		brokerLog(t, b, "throwInterchainBatchStatus", uint64(3), "1356:chain0:0xa", "0xc", true, ""),
+		brokerLog(t, b, "throwReceiptEvent", uint64(3), "1356:chain2:0xc", "1356:chain0:0xa", uint64(pb.IBTP_RECEIPT_SUCCESS), [][][]byte{}, [32]byte{}, []bool{true}),
+		brokerLog(t, b, "throwInterchainBatchStatus", uint64(3), "1356:chain0:0xa", "0xd", true, ""),
+		brokerLog(t, b, "throwReceiptEvent", uint64(3), "1356:chain2:0xd", "1356:chain0:0xa", uint64(pb.IBTP_RECEIPT_FAILURE), [][][]byte{{[]byte("out of balance")}}, [32]byte{}, []bool{false}),
+	}}
+
+	b.interchainBatchStatus(receipt, items)
+	if !items[0].Status || items[0].Reason != "" {
+		t.Fatalf("item 0 %+v, want a plain success", items[0])
+	}
+	if !items[1].Status || items[1].Reason != "RECEIPT_FAILURE: out of balance" {
+		t.Fatalf("item 1 %+v, want the failure receipt as reason", items[1])
+	}
+	if items[2].Status {
+		t.Fatalf("item 2 %+v, want failed without its own status event", items[2])
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"time"
+
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// calldataGas is the intrinsic gas of a non zero calldata byte
+const calldataGas = 16
+
+// batchRequest is a single SubmitIBTP call waiting in the batcher
+type batchRequest struct {
+	from      string
+	serviceID string
+	index     uint64
+	typ       uint64
+	callFunc  string
+	args      [][]byte
+	txStatus  uint64
+	multiSign [][]byte
+	encrypt   bool
+	gas       uint64
+	resp      chan *pb.SubmitIBTPResponse
+}
+
+// ibtpBatcher queues SubmitIBTP calls and flushes them through
+// invokeInterchains when the batch is full in size or gas, or its first
+// request has waited for max_delay. Batches are sent one by one so their
+// nonces follow the order the ibtps came in, while the confirmations are
+// awaited concurrently.
+type ibtpBatcher struct {
+	client   *Client
+	invoker  batchInvoker
+	cfg      Batch
+	requests chan *batchRequest
+	batches  chan []*batchRequest
+}
+
+func newIBTPBatcher(c *Client, invoker batchInvoker, cfg Batch) *ibtpBatcher {
+	return &ibtpBatcher{
+		client:   c,
+		invoker:  invoker,
+		cfg:      cfg,
+		requests: make(chan *batchRequest, cfg.MaxSize),
+		batches:  make(chan []*batchRequest, 16),
+	}
+}
+
+func (b *ibtpBatcher) start() {
+	go b.accumulate()
+	go b.send()
+}
+
+// submit queues an ibtp and blocks until the batch carrying it is confirmed,
+// or the client is stopped.
+func (b *ibtpBatcher) submit(from string, index uint64, serviceID string, typ uint64, callFunc string, args [][]byte, txStatus uint64, multiSign [][]byte, encrypt bool) *pb.SubmitIBTPResponse {
+	req := &batchRequest{
+		from:      from,
+		serviceID: serviceID,
+		index:     index,
+		typ:       typ,
+		callFunc:  callFunc,
+		args:      args,
+		txStatus:  txStatus,
+		multiSign: multiSign,
+		encrypt:   encrypt,
+		resp:      make(chan *pb.SubmitIBTPResponse, 1),
+	}
+	req.gas = b.estimateGas(req)
+
+	select {
+	case b.requests <- req:
+	case <-b.client.ctx.Done():
+		return &pb.SubmitIBTPResponse{Status: false, Message: b.client.ctx.Err().Error()}
+	}
+
+	// the batcher may stop before taking the request from the queue
+	select {
+	case resp := <-req.resp:
+		return resp
+	case <-b.client.ctx.Done():
+		return &pb.SubmitIBTPResponse{Status: false, Message: b.client.ctx.Err().Error()}
+	}
+}
+
+// estimateGas gives a rough cost of an ibtp inside a batch. eth_estimateGas
+// can't be used as queued ibtps depend on the indices of those before them.
+func (b *ibtpBatcher) estimateGas(req *batchRequest) uint64 {
+	data, err := b.client.abi.Pack("invokeInterchain", req.from, req.serviceID, req.index, req.typ,
+		req.callFunc, req.args, req.txStatus, req.multiSign, req.encrypt)
+	if err != nil {
+		return b.cfg.ItemGas
+	}
+	return b.cfg.ItemGas + uint64(len(data))*calldataGas
+}
+
+func (b *ibtpBatcher) accumulate() {
+	var (
+		batch []*batchRequest
+		gas   uint64
+		timer *time.Timer
+		timeC <-chan time.Time
+	)
+
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, timeC = nil, nil
+		}
+		if len(batch) == 0 {
+			return
+		}
+		select {
+		case b.batches <- batch:
+		case <-b.client.ctx.Done():
+			failRequests(batch, b.client.ctx.Err().Error())
+		}
+		batch, gas = nil, 0
+	}
+
+	for {
+		select {
+		case req := <-b.requests:
+			if len(batch) > 0 && gas+req.gas > b.cfg.MaxGas {
+				flush()
+			}
+			if len(batch) == 0 {
+				timer = time.NewTimer(time.Duration(b.cfg.MaxDelay) * time.Millisecond)
+				timeC = timer.C
+			}
+			batch = append(batch, req)
+			gas += req.gas
+			if len(batch) >= b.cfg.MaxSize || gas >= b.cfg.MaxGas {
+				flush()
+			}
+		case <-timeC:
+			timer, timeC = nil, nil
+			flush()
+		case <-b.client.ctx.Done():
+			failRequests(batch, b.client.ctx.Err().Error())
+			return
+		}
+	}
+}
+
+func (b *ibtpBatcher) send() {
+	for {
+		select {
+		case batch := <-b.batches:
+			b.flush(batch)
+		case <-b.client.ctx.Done():
+			for {
+				select {
+				case batch := <-b.batches:
+					failRequests(batch, b.client.ctx.Err().Error())
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (b *ibtpBatcher) flush(batch []*batchRequest) {
+	var (
+		from      = make([]string, len(batch))
+		serviceID = make([]string, len(batch))
+		index     = make([]uint64, len(batch))
+		typ       = make([]uint64, len(batch))
+		callFunc  = make([]string, len(batch))
+		args      = make([][][]byte, len(batch))
+		txStatus  = make([]uint64, len(batch))
+		sign      = make([][][]byte, len(batch))
+		encrypt   = make([]bool, len(batch))
+	)
+	for i, req := range batch {
+		from[i] = req.from
+		serviceID[i] = req.serviceID
+		index[i] = req.index
+		typ[i] = req.typ
+		callFunc[i] = req.callFunc
+		args[i] = req.args
+		txStatus[i] = req.txStatus
+		sign[i] = req.multiSign
+		encrypt[i] = req.encrypt
+	}
+
+	items := newInterchainItems(from, serviceID, index)
+	tx, err := b.client.invokeInterchains(b.invoker, from, serviceID, index, typ, callFunc, args, txStatus, sign, encrypt)
+	if err != nil {
+		failRequests(batch, err.Error())
+		return
+	}
+	logger.Info("Flush ibtp batch", "size", len(batch), "hash", tx.Hash().Hex())
+
+	go func() {
+		b.client.waitForInterchains(b.invoker, tx, items)
+		for i, req := range batch {
+			req.resp <- &pb.SubmitIBTPResponse{Status: items[i].Status, Message: items[i].Reason}
+		}
+	}()
+}
+
+func failRequests(batch []*batchRequest, reason string) {
+	for _, req := range batch {
+		req.resp <- &pb.SubmitIBTPResponse{Status: false, Message: reason}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+func TestBatcherSubmitReturnsOnStop(t *testing.T) {
+	c := &Client{}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	// the batcher is not started, so a queued request is never taken
+	b := newIBTPBatcher(c, nil, Batch{MaxSize: 4, MaxGas: 1000000, ItemGas: 100000, MaxDelay: 100})
+
+	done := make(chan *pb.SubmitIBTPResponse, 1)
+	go func() {
+		done <- b.submit("1356:chain0:0xa", 1, "0xb", 0, "interchainCharge", nil, 0, nil, false)
+	}()
+	select {
+	case resp := <-done:
+		t.Fatalf("submit returned %+v before the batch was sent", resp)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.cancel()
+	select {
+	case resp := <-done:
+		if resp.Status || resp.Message != context.Canceled.Error() {
+			t.Fatalf("response %+v, want failed with %v", resp, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("submit still blocked after the client stopped")
+	}
+}
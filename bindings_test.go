@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBindingsMatchBytecode catches a binding whose ABI was changed without
+// regenerating it: the dispatcher of the deployed bytecode pushes the selector
+// of every function, so a selector missing from the bytecode means DeployBroker
+// deploys a contract reverting on that function. Run go generate to fix it.
+func TestBindingsMatchBytecode(t *testing.T) {
+	for _, binding := range []struct {
+		name string
+		sigs map[string]string
+		bin  string
+	}{
+		{name: "Broker", sigs: BrokerFuncSigs, bin: BrokerBin},
+		{name: "BrokerDirect", sigs: BrokerDirectFuncSigs, bin: BrokerDirectBin},
+	} {
+		for selector, sig := range binding.sigs {
+			if !strings.Contains(binding.bin, selector) {
+				t.Errorf("%s bytecode has no %s (%s), regenerate the binding", binding.name, sig, selector)
+			}
+		}
+	}
+}
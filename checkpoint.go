@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	checkpointKeyPrefix     = "checkpoint-"
+	locationKeyPrefix       = "location-"
+	locationHeightKeyPrefix = "location-height-"
+	headerKeyPrefix         = "header-"
+
+	// locationRetention is how many blocks below the checkpoint the event
+	// locations are kept, older ones are pruned as their IBTPs are
+	// rarely queried again
+	locationRetention = 100000
+)
+
+// Checkpoint is the position of the last broker event which has been
+// converted to IBTP and pushed to pier.
+type Checkpoint struct {
+	Height   uint64 `json:"height"`
+	LogIndex uint   `json:"log_index"`
+}
+
+// Before reports whether log is at or before the checkpoint
+func (cp *Checkpoint) Before(log types.Log) bool {
+	if cp == nil {
+		return false
+	}
+	if log.BlockNumber != cp.Height {
+		return log.BlockNumber < cp.Height
+	}
+	return log.Index <= cp.LogIndex
+}
+
+// Location is the position of a broker event on chain, kept so the event can
+// be proven when its IBTP is queried again
+type Location struct {
+	BlockHash common.Hash `json:"block_hash"`
+	Height    uint64      `json:"height"`
+	TxIndex   uint        `json:"tx_index"`
+	LogIndex  uint        `json:"log_index"`
+}
+
+// HeaderCheckpoint is the last block header emitted as update meta
+type HeaderCheckpoint struct {
+	Height uint64      `json:"height"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// CheckpointStore persists the consumer checkpoint of a broker contract, the
+// locations of its events and the header stream position
+type CheckpointStore struct {
+	db   *leveldb.DB
+	addr string
+	key  []byte
+	last *Checkpoint
+	lock sync.Mutex
+}
+
+func NewCheckpointStore(path string, contractAddr string) (*CheckpointStore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint store %s: %w", path, err)
+	}
+
+	store := &CheckpointStore{
+		db:   db,
+		addr: strings.ToLower(contractAddr),
+		key:  []byte(checkpointKeyPrefix + strings.ToLower(contractAddr)),
+	}
+	store.last, err = store.Load()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Load returns the stored checkpoint, or nil if there is none
+func (s *CheckpointStore) Load() (*Checkpoint, error) {
+	data, err := s.db.Get(s.key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get checkpoint: %w", err)
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("unmarshal checkpoint: %w", err)
+	}
+
+	return cp, nil
+}
+
+// Save records log as processed if it is after the current checkpoint
+func (s *CheckpointStore) Save(log types.Log) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.last.Before(log) {
+		return nil
+	}
+
+	cp := &Checkpoint{
+		Height:   log.BlockNumber,
+		LogIndex: log.Index,
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Put(s.key, data, nil); err != nil {
+		return fmt.Errorf("put checkpoint: %w", err)
+	}
+	s.last = cp
+
+	if cp.Height > locationRetention {
+		if err := s.pruneLocations(cp.Height - locationRetention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CheckpointStore) locationKey(id string) []byte {
+	return []byte(locationKeyPrefix + s.addr + "-" + id)
+}
+
+// locationHeightKey indexes the location of the event with id by its height,
+// so the locations are pruned in the order of heights
+func (s *CheckpointStore) locationHeightKey(height uint64, id string) []byte {
+	key := []byte(locationHeightKeyPrefix + s.addr + "-")
+	key = append(key, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(key)-8:], height)
+	return append(key, id...)
+}
+
+// SaveLocation records where the event with id was emitted
+func (s *CheckpointStore) SaveLocation(id string, log types.Log) error {
+	data, err := json.Marshal(&Location{
+		BlockHash: log.BlockHash,
+		Height:    log.BlockNumber,
+		TxIndex:   log.TxIndex,
+		LogIndex:  log.Index,
+	})
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	batch.Put(s.locationKey(id), data)
+	batch.Put(s.locationHeightKey(log.BlockNumber, id), nil)
+	if err := s.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("put location of %s: %w", id, err)
+	}
+	return nil
+}
+
+// EarliestLocation returns the height of the lowest recorded event location,
+// or false if none is recorded
+func (s *CheckpointStore) EarliestLocation() (uint64, bool, error) {
+	prefix := []byte(locationHeightKeyPrefix + s.addr + "-")
+	iter := s.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	if !iter.Next() {
+		if err := iter.Error(); err != nil {
+			return 0, false, fmt.Errorf("iterate locations: %w", err)
+		}
+		return 0, false, nil
+	}
+	return binary.BigEndian.Uint64(iter.Key()[len(prefix):]), true, nil
+}
+
+// pruneLocations deletes the locations of the events below height. An index
+// entry left by an event mined again at another height only deletes its own
+// entry.
+func (s *CheckpointStore) pruneLocations(height uint64) error {
+	prefix := []byte(locationHeightKeyPrefix + s.addr + "-")
+	iter := s.db.NewIterator(&util.Range{Start: prefix, Limit: s.locationHeightKey(height, "")}, nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		key := iter.Key()
+		batch.Delete(append([]byte{}, key...))
+		id := string(key[len(prefix)+8:])
+		loc, err := s.LoadLocation(id)
+		if err != nil {
+			return err
+		}
+		if loc != nil && loc.Height < height {
+			batch.Delete(s.locationKey(id))
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("iterate locations: %w", err)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("prune locations below %d: %w", height, err)
+	}
+	return nil
+}
+
+// LoadLocation returns where the event with id was emitted, or nil if it is
+// unknown
+func (s *CheckpointStore) LoadLocation(id string) (*Location, error) {
+	data, err := s.db.Get(s.locationKey(id), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get location of %s: %w", id, err)
+	}
+
+	loc := &Location{}
+	if err := json.Unmarshal(data, loc); err != nil {
+		return nil, fmt.Errorf("unmarshal location of %s: %w", id, err)
+	}
+	return loc, nil
+}
+
+// SaveHeader records the last header emitted as update meta
+func (s *CheckpointStore) SaveHeader(height uint64, hash common.Hash) error {
+	data, err := json.Marshal(&HeaderCheckpoint{Height: height, Hash: hash})
+	if err != nil {
+		return err
+	}
+	if err := s.db.Put([]byte(headerKeyPrefix+s.addr), data, nil); err != nil {
+		return fmt.Errorf("put header checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadHeader returns the last header emitted as update meta, or nil if none
+// has been
+func (s *CheckpointStore) LoadHeader() (*HeaderCheckpoint, error) {
+	data, err := s.db.Get([]byte(headerKeyPrefix+s.addr), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get header checkpoint: %w", err)
+	}
+
+	cp := &HeaderCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("unmarshal header checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+func (s *CheckpointStore) Reset() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.db.Delete(s.key, nil); err != nil {
+		return fmt.Errorf("delete checkpoint: %w", err)
+	}
+	s.last = nil
+
+	return nil
+}
+
+func (s *CheckpointStore) Close() error {
+	return s.db.Close()
+}
+
+// Last returns the checkpoint in memory, or nil if nothing is recorded
+func (s *CheckpointStore) Last() *Checkpoint {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.last
+}
+
+func (c *Client) saveCheckpoint(log types.Log) {
+	if err := c.checkpoint.Save(log); err != nil {
+		logger.Warn("save checkpoint", "height", log.BlockNumber, "index", log.Index, "err", err.Error())
+	}
+}
+
+// backfillStart returns the height from which history events are replayed
+// and the checkpoint before which events have been processed already.
+// Backfill is disabled if the height is 0.
+func (c *Client) backfillStart() (uint64, *Checkpoint) {
+	if cp := c.checkpoint.Last(); cp != nil {
+		return cp.Height, cp
+	}
+	return c.config.Ether.StartHeight, nil
+}
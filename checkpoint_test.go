@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestCheckpointPrunesLocations(t *testing.T) {
+	store, err := NewCheckpointStore(t.TempDir(), "0x01")
+	if err != nil {
+		t.Fatalf("open checkpoint store: %v", err)
+	}
+	defer store.Close()
+
+	for _, loc := range []struct {
+		id     string
+		height uint64
+	}{
+		{"old", 10},
+		{"recent", 150000},
+		// mined again at a later height by a reorg
+		{"moved", 20},
+		{"moved", 200010},
+	} {
+		if err := store.SaveLocation(loc.id, types.Log{BlockNumber: loc.height}); err != nil {
+			t.Fatalf("save location of %s: %v", loc.id, err)
+		}
+	}
+
+	if err := store.Save(types.Log{BlockNumber: 200000}); err != nil {
+		t.Fatalf("save checkpoint: %v", err)
+	}
+
+	for id, wantHeight := range map[string]uint64{"old": 0, "recent": 150000, "moved": 200010} {
+		loc, err := store.LoadLocation(id)
+		if err != nil {
+			t.Fatalf("load location of %s: %v", id, err)
+		}
+		switch {
+		case wantHeight == 0 && loc != nil:
+			t.Errorf("location of %s at %d kept past the retention", id, loc.Height)
+		case wantHeight != 0 && (loc == nil || loc.Height != wantHeight):
+			t.Errorf("location of %s is %+v, want height %d", id, loc, wantHeight)
+		}
+	}
+}
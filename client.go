@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -11,17 +12,18 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Rican7/retry"
 	"github.com/Rican7/retry/strategy"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/hashicorp/go-hclog"
 	"github.com/meshplus/bitxhub-core/agency"
 	"github.com/meshplus/bitxhub-model/pb"
@@ -30,16 +32,33 @@ import (
 //go:generate abigen --sol ./example/broker.sol --pkg main --out broker.go
 //go:generate abigen --sol ./example/broker_direct.sol --pkg main --out broker_direct.go
 type Client struct {
-	abi           abi.ABI
-	config        *Config
-	ctx           context.Context
-	cancel        context.CancelFunc
-	ethClient     *ethclient.Client
-	session       *BrokerSession
-	sessionDirect *BrokerDirectSession
-	eventC        chan *pb.IBTP
-	reqCh         chan *pb.GetDataRequest
-	lock          sync.Mutex
+	abi          abi.ABI
+	config       *Config
+	configPath   string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	conn         atomic.Value
+	endpoints    *endpointPool
+	broker       brokerContract
+	heads        *headTracker
+	confirm      confirmPolicy
+	eventC       chan *pb.IBTP
+	reqCh        chan *pb.GetDataRequest
+	updateMeta   chan *pb.UpdateMeta
+	streamOnce   sync.Once
+	checkpoint   *CheckpointStore
+	reconnects   uint64
+	outProgress  *outProgress
+	consumed     uint64
+	nonces       *nonceManager
+	fee          feeStrategy
+	gas          *gasPolicy
+	customErrors map[string]*customError
+	batcher      *ibtpBatcher
+	receipts     receiptCache
+	validators   validatorCache
+	recovered    uint64
+	lock         sync.Mutex
 }
 
 var (
@@ -55,10 +74,35 @@ var (
 const (
 	SubmitIBTPErr    = "SubmitIBTP tx execution failed"
 	SubmitReceiptErr = "SubmitReceipt tx execution failed"
+
+	// txDroppedChecks is how many times in a row a tx is found neither on
+	// chain nor in mempool before it is regarded as dropped
+	txDroppedChecks = 5
+
+	// bestBlockAttempts is how many times the chain head is fetched before
+	// the call needing it fails
+	bestBlockAttempts = 3
+)
+
+var (
+	errTxDropped  = errors.New("tx dropped from mempool")
+	errTxReplaced = errors.New("tx replaced by another one with the same nonce")
 )
 
-func (c *Client) GetUpdateMeta() chan *pb.UpdateMeta {
-	panic("implement me")
+// loadKey decrypts the keystore file at keyPath with the password kept in
+// passwordPath
+func loadKey(keyPath, passwordPath string) (*keystore.Key, error) {
+	keyByte, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := ioutil.ReadFile(passwordPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return keystore.DecryptKey(keyByte, strings.TrimSpace(string(password)))
 }
 
 func (c *Client) Initialize(configPath string, _ []byte, mode string) error {
@@ -71,24 +115,30 @@ func (c *Client) Initialize(configPath string, _ []byte, mode string) error {
 		"broker address", cfg.Ether.ContractAddress,
 		"ethereum node ip", cfg.Ether.Addr)
 
-	etherCli, err := ethclient.Dial(cfg.Ether.Addr)
+	fee, err := newFeeStrategy(cfg.Fee)
 	if err != nil {
-		return fmt.Errorf("dial ethereum node: %w", err)
+		return err
 	}
 
-	keyPath := filepath.Join(configPath, cfg.Ether.KeyPath)
-	keyByte, err := ioutil.ReadFile(keyPath)
+	confirm, err := parseConfirmPolicy(cfg.Ether.Confirmation, cfg.Ether.MinConfirm)
 	if err != nil {
 		return err
 	}
 
-	psdPath := filepath.Join(configPath, cfg.Ether.Password)
-	password, err := ioutil.ReadFile(psdPath)
+	endpoints, err := newEndpointPool(cfg.Ether.Addr, cfg.Health)
 	if err != nil {
 		return err
 	}
+	endpoints.probeAll(context.TODO())
+	active := endpoints.best()
+	if active == nil {
+		return errNoEndpoint
+	}
+	endpoints.setCurrent(active)
+	rpcCli, etherCli := active.clients()
+	c.conn.Store(newNodeConn(active, rpcCli, etherCli))
 
-	unlockedKey, err := keystore.DecryptKey(keyByte, strings.TrimSpace(string(password)))
+	unlockedKey, err := loadKey(filepath.Join(configPath, cfg.Ether.KeyPath), filepath.Join(configPath, cfg.Ether.Password))
 	if err != nil {
 		return err
 	}
@@ -107,32 +157,25 @@ func (c *Client) Initialize(configPath string, _ []byte, mode string) error {
 		auth.Context = context.TODO()
 	}
 	auth.Value = nil
+	var broker brokerContract
+	backend := &nodeBackend{client: c}
 	if mode == relayMode {
-		broker, err := NewBroker(common.HexToAddress(cfg.Ether.ContractAddress), etherCli)
-		if err != nil {
-			return fmt.Errorf("failed to instantiate a Broker contract: %w", err)
-		}
-		session := &BrokerSession{
-			Contract: broker,
-			CallOpts: bind.CallOpts{
-				Pending: false,
-			},
-			TransactOpts: *auth,
-		}
-		c.session = session
+		broker, err = newRelayBroker(common.HexToAddress(cfg.Ether.ContractAddress), backend, auth)
 	} else {
-		broker, err := NewBrokerDirect(common.HexToAddress(cfg.Ether.ContractAddress), etherCli)
-		if err != nil {
-			return fmt.Errorf("failed to instantiate a Broker contract: %w", err)
-		}
-		sessionDirect := &BrokerDirectSession{
-			Contract: broker,
-			CallOpts: bind.CallOpts{
-				Pending: false,
-			},
-			TransactOpts: *auth,
-		}
-		c.sessionDirect = sessionDirect
+		broker, err = newDirectBroker(common.HexToAddress(cfg.Ether.ContractAddress), backend, auth)
+	}
+	if err != nil {
+		return err
+	}
+
+	customErrors, err := parseCustomErrors(broker.abiJSON())
+	if err != nil {
+		return err
+	}
+
+	gas, err := newGasPolicy(cfg.Gas, broker.abiJSON())
+	if err != nil {
+		return err
 	}
 
 	ab, err := abi.JSON(bytes.NewReader([]byte(BrokerABI)))
@@ -140,25 +183,56 @@ func (c *Client) Initialize(configPath string, _ []byte, mode string) error {
 		return fmt.Errorf("abi unmarshal: %s", err.Error())
 	}
 
+	checkpoint, err := NewCheckpointStore(filepath.Join(configPath, cfg.Ether.CheckpointPath), cfg.Ether.ContractAddress)
+	if err != nil {
+		return err
+	}
+
 	c.config = cfg
+	c.configPath = configPath
+	c.broker = broker
+	c.checkpoint = checkpoint
+	c.outProgress = newOutProgress()
+	c.nonces = newNonceManager(auth.From)
+	c.fee = fee
+	c.confirm = confirm
+	c.gas = gas
+	c.customErrors = customErrors
 	c.eventC = make(chan *pb.IBTP, 1024)
 	c.reqCh = make(chan *pb.GetDataRequest, 1024)
-	c.ethClient = etherCli
+	c.updateMeta = make(chan *pb.UpdateMeta, 1024)
+	c.endpoints = endpoints
 	c.abi = ab
 	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.heads = newHeadTracker(c)
+	if cfg.Batch.Enable {
+		if invoker, ok := broker.(batchInvoker); ok {
+			c.batcher = newIBTPBatcher(c, invoker, cfg.Batch)
+		} else {
+			logger.Warn("IBTP batching is unsupported", "mode", broker.mode())
+		}
+	}
 	return nil
 }
 
 func (c *Client) Start() error {
-	if c.session == nil {
-		return c.StartDirectConsumer()
+	c.heads.start()
+	if err := c.broker.consume(c); err != nil {
+		return err
 	}
-	return c.StartConsumer()
+
+	if c.batcher != nil {
+		c.batcher.start()
+	}
+	go c.startReconciler()
+	go c.checkEndpoints()
+	go c.watchValidators()
+	return nil
 }
 
 func (c *Client) Stop() error {
 	c.cancel()
-	return nil
+	return c.checkpoint.Close()
 }
 
 func (c *Client) GetIBTPCh() chan *pb.IBTP {
@@ -214,6 +288,12 @@ func (c *Client) SubmitIBTP(from string, index uint64, serviceID string, ibtpTyp
 			Args = append(Args, content.Args[i:i+num])
 			i += num
 		}
+		if err := c.checkMultiInterchainSign(from, index, serviceID, uint64(ibtpType), content.Func, Args, uint64(proof.TxStatus), proof.MultiSign); err != nil {
+			ret.Status = false
+			ret.Message = err.Error()
+			logger.Warn("SubmitIBTP:", ret.Status, ret.Message)
+			return ret, nil
+		}
 		receipt, err := c.InvokeMultiInterchain(from, index, serviceID, uint64(ibtpType), content.Func, Args, uint64(proof.TxStatus), proof.MultiSign, isEncrypted)
 		if err != nil {
 			ret.Status = false
@@ -223,12 +303,23 @@ func (c *Client) SubmitIBTP(from string, index uint64, serviceID string, ibtpTyp
 		}
 		if receipt.Status != types.ReceiptStatusSuccessful {
 			ret.Status = false
-			ret.Message = SubmitIBTPErr
+			ret.Message = c.failureMessage(SubmitIBTPErr, receipt)
 			return ret, nil
 		}
 		logger.Info("SubmitIBTP:", ret.Status, ret.Message, "txHash: ", receipt.TxHash)
 	} else {
 		content.Args = content.Args[1:]
+		if err := c.checkInterchainSign(from, index, serviceID, uint64(ibtpType), content.Func, content.Args, uint64(proof.TxStatus), proof.MultiSign); err != nil {
+			ret.Status = false
+			ret.Message = err.Error()
+			logger.Warn("SubmitIBTP:", ret.Status, ret.Message)
+			return ret, nil
+		}
+		if c.batcher != nil {
+			ret = c.batcher.submit(from, index, serviceID, uint64(ibtpType), content.Func, content.Args, uint64(proof.TxStatus), proof.MultiSign, isEncrypted)
+			logger.Info("SubmitIBTP:", ret.Status, ret.Message)
+			return ret, nil
+		}
 		receipt, err := c.invokeInterchain(from, index, serviceID, uint64(ibtpType), content.Func, content.Args, uint64(proof.TxStatus), proof.MultiSign, isEncrypted)
 		if err != nil {
 			ret.Status = false
@@ -239,7 +330,7 @@ func (c *Client) SubmitIBTP(from string, index uint64, serviceID string, ibtpTyp
 
 		if receipt.Status != types.ReceiptStatusSuccessful {
 			ret.Status = false
-			ret.Message = SubmitIBTPErr
+			ret.Message = c.failureMessage(SubmitIBTPErr, receipt)
 			return ret, nil
 		}
 		logger.Info("SubmitIBTP:", ret.Status, ret.Message, "txHash: ", receipt.TxHash)
@@ -282,6 +373,11 @@ func (c *Client) SubmitReceipt(to string, index uint64, serviceID string, ibtpTy
 	for _, s := range result.Data {
 		results = append(results, s.Data)
 	}
+	if err := c.checkReceiptSign(serviceID, to, index, uint64(ibtpType), results, uint64(proof.TxStatus), proof.MultiSign); err != nil {
+		ret.Status = false
+		ret.Message = err.Error()
+		return ret, nil
+	}
 
 	// if src chain need rollback, the length of results is 0
 	if len(result.MultiStatus) > 1 || (len(result.MultiStatus) == 0 && proof.TxStatus != pb.TransactionStatus_BEGIN) {
@@ -294,7 +390,7 @@ func (c *Client) SubmitReceipt(to string, index uint64, serviceID string, ibtpTy
 
 		if receipt.Status != types.ReceiptStatusSuccessful {
 			ret.Status = false
-			ret.Message = SubmitReceiptErr
+			ret.Message = c.failureMessage(SubmitReceiptErr, receipt)
 		}
 
 	} else {
@@ -308,23 +404,30 @@ func (c *Client) SubmitReceipt(to string, index uint64, serviceID string, ibtpTy
 
 		if receipt.Status != types.ReceiptStatusSuccessful {
 			ret.Status = false
-			ret.Message = SubmitReceiptErr
+			ret.Message = c.failureMessage(SubmitReceiptErr, receipt)
 		}
 	}
 
 	return ret, nil
 }
 
+// SubmitIBTPBatch packs several ibtps into one invokeInterchains tx. The
+// response reports the status of every (from, index) pair so that only the
+// ibtps the broker did not apply need to be submitted again.
 func (c *Client) SubmitIBTPBatch(from []string, index []uint64, serviceID []string, ibtpType []pb.IBTP_Type, content []*pb.Content, proof []*pb.BxhProof, isEncrypted []bool) (*pb.SubmitIBTPResponse, error) {
-	ret := &pb.SubmitIBTPResponse{Status: true}
+	items := newInterchainItems(from, serviceID, index)
+	invoker, ok := c.broker.(batchInvoker)
+	if !ok {
+		failBatch(items, unsupported("SubmitIBTPBatch", c.broker).Error())
+		return batchResponse(items), nil
+	}
+
 	var (
 		callFunc []string
 		args     [][][]byte
 		typ      []uint64
 		txStatus []uint64
 		sign     [][][]byte
-		tx       *types.Transaction
-		txErr    error
 	)
 	for idx, ct := range content {
 		callFunc = append(callFunc, ct.Func)
@@ -335,50 +438,143 @@ func (c *Client) SubmitIBTPBatch(from []string, index []uint64, serviceID []stri
 		sign = append(sign, proof[idx].MultiSign)
 	}
 
+	tx, err := c.invokeInterchains(invoker, from, serviceID, index, typ, callFunc, args, txStatus, sign, isEncrypted)
+	if err != nil {
+		failBatch(items, err.Error())
+		return batchResponse(items), nil
+	}
+
+	c.waitForInterchains(invoker, tx, items)
+	ret := batchResponse(items)
+	logger.Info("SubmitIBTPBatch:", ret.Status, ret.Message, "txHash: ", tx.Hash())
+	return ret, nil
+}
+
+// invokeInterchains sends one invokeInterchains tx carrying all given ibtps.
+func (c *Client) invokeInterchains(invoker batchInvoker, from []string, serviceID []string, index []uint64, typ []uint64, callFunc []string, args [][][]byte, txStatus []uint64, sign [][][]byte, isEncrypted []bool) (*types.Transaction, error) {
+	var tx *types.Transaction
+	var txErr error
 	if err := retry.Retry(func(attempt uint) error {
-		tx, txErr = c.session.InvokeInterchains(from, serviceID, index, typ, callFunc, args, txStatus, sign, isEncrypted)
+		tx, txErr = c.sendTx(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return invoker.invokeInterchains(opts, from, serviceID, index, typ, callFunc, args, txStatus, sign, isEncrypted)
+		})
 		if txErr != nil {
-			if strings.Contains(txErr.Error(), "execution reverted") {
+			logger.Warn("Call InvokeInterchains failed",
+				"size", strconv.Itoa(len(from)),
+				"error", txErr.Error(),
+			)
+
+			if giveUpSend(txErr, attempt) {
 				return nil
 			}
 		}
 
 		return txErr
-	}, strategy.Wait(2*time.Second)); err != nil {
+	}, sendRetryWait(&txErr)); err != nil {
+		logger.Error("Can't invoke contract", "error", err)
+	}
+
+	return tx, txErr
+}
+
+// waitForInterchains waits for an invokeInterchains tx to be confirmed and
+// fills the items with the status of each ibtp.
+func (c *Client) waitForInterchains(invoker batchInvoker, tx *types.Transaction, items []*batchItem) {
+	receipt, err := c.waitForConfirmed(tx)
+	if err != nil {
+		failBatch(items, err.Error())
+		return
+	}
+
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		failBatch(items, c.failureMessage(SubmitIBTPErr, receipt))
+		return
+	}
+
+	invoker.interchainBatchStatus(receipt, items)
+}
+
+// SubmitReceiptBatch packs several receipts into one invokeReceipts tx. The
+// broker runs each receipt on its own, so the response reports the status of
+// every (to, index) pair instead of failing the whole batch.
+func (c *Client) SubmitReceiptBatch(to []string, index []uint64, serviceID []string, ibtpType []pb.IBTP_Type, result []*pb.Result, proof []*pb.BxhProof) (*pb.SubmitIBTPResponse, error) {
+	items := newReceiptItems(serviceID, to, index)
+	invoker, ok := c.broker.(batchInvoker)
+	if !ok {
+		failBatch(items, unsupported("SubmitReceiptBatch", c.broker).Error())
+		return batchResponse(items), nil
+	}
+
+	var (
+		typ         []uint64
+		results     [][][][]byte
+		multiStatus [][]bool
+		txStatus    []uint64
+		sign        [][][]byte
+		tx          *types.Transaction
+		txErr       error
+	)
+	for idx, rs := range result {
+		var res [][][]byte
+		for _, s := range rs.Data {
+			res = append(res, s.Data)
+		}
+		results = append(results, res)
+		multiStatus = append(multiStatus, rs.MultiStatus)
+		typ = append(typ, uint64(ibtpType[idx]))
+		txStatus = append(txStatus, uint64(proof[idx].TxStatus))
+		sign = append(sign, proof[idx].MultiSign)
+	}
+
+	if err := retry.Retry(func(attempt uint) error {
+		tx, txErr = c.sendTx(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return invoker.invokeReceipts(opts, serviceID, to, index, typ, results, multiStatus, txStatus, sign)
+		})
+		if txErr != nil {
+			logger.Warn("Call InvokeReceipts failed",
+				"size", strconv.Itoa(len(to)),
+				"error", txErr.Error(),
+			)
+
+			if giveUpSend(txErr, attempt) {
+				return nil
+			}
+		}
+
+		return txErr
+	}, sendRetryWait(&txErr)); err != nil {
 		logger.Error("Can't invoke contract", "error", err)
 	}
 	if txErr != nil {
-		ret.Status = false
-		ret.Message = txErr.Error()
-		return ret, nil
+		failBatch(items, txErr.Error())
+		return batchResponse(items), nil
 	}
 
-	receipt := c.waitForConfirmed(tx.Hash())
+	receipt, err := c.waitForConfirmed(tx)
+	if err != nil {
+		failBatch(items, err.Error())
+		return batchResponse(items), nil
+	}
 
 	if receipt.Status != types.ReceiptStatusSuccessful {
-		ret.Status = false
-		ret.Message = SubmitIBTPErr
-		return ret, nil
+		failBatch(items, c.failureMessage(SubmitReceiptErr, receipt))
+		return batchResponse(items), nil
 	}
 
+	invoker.receiptBatchStatus(receipt, items)
+	ret := batchResponse(items)
+	logger.Info("SubmitReceiptBatch:", ret.Status, ret.Message, "txHash: ", receipt.TxHash)
 	return ret, nil
 }
 
-func (c *Client) SubmitReceiptBatch(_ []string, _ []uint64, _ []string, _ []pb.IBTP_Type, _ []*pb.Result, _ []*pb.BxhProof) (*pb.SubmitIBTPResponse, error) {
-	panic("implement me")
-}
-
 //nolint:dupl
 func (c *Client) invokeInterchain(srcFullID string, index uint64, destAddr string, reqType uint64, callFunc string, args [][]byte, txStatus uint64, multiSign [][]byte, encrypt bool) (*types.Receipt, error) {
-	c.lock.Lock()
 	var tx *types.Transaction
 	var txErr error
 	if err := retry.Retry(func(attempt uint) error {
-		if c.session == nil {
-			tx, txErr = c.sessionDirect.InvokeInterchain(srcFullID, destAddr, index, reqType, callFunc, args, txStatus, multiSign, encrypt)
-		} else {
-			tx, txErr = c.session.InvokeInterchain(srcFullID, destAddr, index, reqType, callFunc, args, txStatus, multiSign, encrypt)
-		}
+		tx, txErr = c.sendTx(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return c.broker.invokeInterchain(opts, srcFullID, destAddr, index, reqType, callFunc, args, txStatus, multiSign, encrypt)
+		})
 		if txErr != nil {
 			logger.Warn("Call InvokeInterchain failed",
 				"srcFullID", srcFullID,
@@ -401,21 +597,20 @@ func (c *Client) invokeInterchain(srcFullID string, index uint64, destAddr strin
 				logger.Warn("multiSign", strconv.Itoa(i), hexutil.Encode(sign))
 			}
 
-			if strings.Contains(txErr.Error(), "execution reverted") {
+			if giveUpSend(txErr, attempt) {
 				return nil
 			}
 		}
 
 		return txErr
-	}, strategy.Wait(2*time.Second)); err != nil {
+	}, sendRetryWait(&txErr)); err != nil {
 		logger.Error("Can't invoke contract", "error", err)
 	}
-	c.lock.Unlock()
 
 	if txErr != nil {
 		return nil, txErr
 	}
-	return c.waitForConfirmed(tx.Hash()), nil
+	return c.waitForConfirmed(tx)
 }
 
 //nolint:dupl
@@ -424,15 +619,12 @@ func (c *Client) InvokeMultiInterchain(srcFullID string, index uint64, destAddr
 	for i := 0; i < len(args); i++ {
 		arg[i] = bytes.Join(args[i], []byte(","))
 	}
-	c.lock.Lock()
 	var tx *types.Transaction
 	var txErr error
 	if err := retry.Retry(func(attempt uint) error {
-		if c.session == nil {
-			tx, txErr = c.sessionDirect.InvokeMultiInterchain(srcFullID, destAddr, index, reqType, callFunc, args, txStatus, multiSign, encrypt)
-		} else {
-			tx, txErr = c.session.InvokeMultiInterchain(srcFullID, destAddr, index, reqType, callFunc, args, txStatus, multiSign, encrypt)
-		}
+		tx, txErr = c.sendTx(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return c.broker.invokeMultiInterchain(opts, srcFullID, destAddr, index, reqType, callFunc, args, txStatus, multiSign, encrypt)
+		})
 		if txErr != nil {
 			logger.Warn("Call InvokeMultiInterchain failed",
 				"srcFullID", srcFullID,
@@ -455,21 +647,20 @@ func (c *Client) InvokeMultiInterchain(srcFullID string, index uint64, destAddr
 				logger.Warn("multiSign", strconv.Itoa(i), hexutil.Encode(sign))
 			}
 
-			if strings.Contains(txErr.Error(), "execution reverted") {
+			if giveUpSend(txErr, attempt) {
 				return nil
 			}
 		}
 
 		return txErr
-	}, strategy.Wait(2*time.Second)); err != nil {
+	}, sendRetryWait(&txErr)); err != nil {
 		logger.Error("Can't invoke contract", "error", err)
 	}
-	c.lock.Unlock()
 
 	if txErr != nil {
 		return nil, txErr
 	}
-	return c.waitForConfirmed(tx.Hash()), nil
+	return c.waitForConfirmed(tx)
 }
 
 func (c *Client) invokeReceipt(srcAddr string, dstFullID string, index uint64, reqType uint64, results [][][]byte, txStatus uint64, multiSign [][]byte) (*types.Receipt, error) {
@@ -477,15 +668,12 @@ func (c *Client) invokeReceipt(srcAddr string, dstFullID string, index uint64, r
 	for i := 0; i < len(results); i++ {
 		result[i] = bytes.Join(results[i], []byte(","))
 	}
-	c.lock.Lock()
 	var tx *types.Transaction
 	var txErr error
 	if err := retry.Retry(func(attempt uint) error {
-		if c.session == nil {
-			tx, txErr = c.sessionDirect.InvokeReceipt(srcAddr, dstFullID, index, reqType, results, txStatus, multiSign)
-		} else {
-			tx, txErr = c.session.InvokeReceipt(srcAddr, dstFullID, index, reqType, results, txStatus, multiSign)
-		}
+		tx, txErr = c.sendTx(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return c.broker.invokeReceipt(opts, srcAddr, dstFullID, index, reqType, results, txStatus, multiSign)
+		})
 		if txErr != nil {
 			logger.Warn("Call InvokeReceipt failed",
 				"srcAddr", srcAddr,
@@ -506,21 +694,20 @@ func (c *Client) invokeReceipt(srcAddr string, dstFullID string, index uint64, r
 				logger.Warn("multiSign", strconv.Itoa(i), hexutil.Encode(sign))
 			}
 
-			if strings.Contains(txErr.Error(), "execution reverted") {
+			if giveUpSend(txErr, attempt) {
 				return nil
 			}
 		}
 
 		return txErr
-	}, strategy.Wait(2*time.Second)); err != nil {
+	}, sendRetryWait(&txErr)); err != nil {
 		logger.Error("Can't invoke contract", "error", err)
 	}
-	c.lock.Unlock()
 	if txErr != nil {
 		return nil, txErr
 	}
 
-	return c.waitForConfirmed(tx.Hash()), nil
+	return c.waitForConfirmed(tx)
 }
 
 func (c *Client) InvokeMultiReceipt(srcAddr string, destFullID string, index uint64, reqType uint64, results [][][]byte, multiStatus []bool, txStatus uint64, multiSign [][]byte) (*types.Receipt, error) {
@@ -528,15 +715,12 @@ func (c *Client) InvokeMultiReceipt(srcAddr string, destFullID string, index uin
 	for i := 0; i < len(results); i++ {
 		result[i] = bytes.Join(results[i], []byte(","))
 	}
-	c.lock.Lock()
 	var tx *types.Transaction
 	var txErr error
 	if err := retry.Retry(func(attempt uint) error {
-		if c.session == nil {
-			tx, txErr = c.sessionDirect.InvokeMultiReceipt(srcAddr, destFullID, index, reqType, results, multiStatus, txStatus, multiSign)
-		} else {
-			tx, txErr = c.session.InvokeMultiReceipt(srcAddr, destFullID, index, reqType, results, multiStatus, txStatus, multiSign)
-		}
+		tx, txErr = c.sendTx(func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return c.broker.invokeMultiReceipt(opts, srcAddr, destFullID, index, reqType, results, multiStatus, txStatus, multiSign)
+		})
 		if txErr != nil {
 			logger.Warn("Call InvokeReceipt failed",
 				"srcAddr", srcAddr,
@@ -557,21 +741,20 @@ func (c *Client) InvokeMultiReceipt(srcAddr string, destFullID string, index uin
 				logger.Warn("multiSign", strconv.Itoa(i), hexutil.Encode(sign))
 			}
 
-			if strings.Contains(txErr.Error(), "execution reverted") {
+			if giveUpSend(txErr, attempt) {
 				return nil
 			}
 		}
 
 		return txErr
-	}, strategy.Wait(2*time.Second)); err != nil {
+	}, sendRetryWait(&txErr)); err != nil {
 		logger.Error("Can't invoke contract", "error", err)
 	}
-	c.lock.Unlock()
 	if txErr != nil {
 		return nil, txErr
 	}
 
-	return c.waitForConfirmed(tx.Hash()), nil
+	return c.waitForConfirmed(tx)
 }
 
 // GetOutMessage gets crosschain tx by `to` address and index
@@ -581,23 +764,7 @@ func (c *Client) GetOutMessage(servicePair string, idx uint64) (*pb.IBTP, error)
 		return nil, err
 	}
 
-	if c.session == nil {
-		ev := &BrokerDirectThrowInterchainEvent{
-			Index:     idx,
-			DstFullID: dstService,
-			SrcFullID: srcService,
-		}
-
-		return c.Convert2DirectIBTP(ev, int64(c.config.Ether.TimeoutHeight))
-	} else {
-		ev := &BrokerThrowInterchainEvent{
-			Index:     idx,
-			DstFullID: dstService,
-			SrcFullID: srcService,
-		}
-
-		return c.Convert2IBTP(ev, int64(c.config.Ether.TimeoutHeight))
-	}
+	return c.broker.outMessage(c, srcService, dstService, idx)
 }
 
 // GetReceiptMessage gets the execution results from contract by from-index key
@@ -611,11 +778,7 @@ func (c *Client) GetReceiptMessage(servicePair string, idx uint64) (*pb.IBTP, er
 
 	if err := retry.Retry(func(attempt uint) error {
 		var err error
-		if c.session == nil {
-			data, typ, encrypt, multiStatus, err = c.sessionDirect.GetReceiptMessage(servicePair, idx)
-		} else {
-			data, typ, encrypt, multiStatus, err = c.session.GetReceiptMessage(servicePair, idx)
-		}
+		data, typ, encrypt, multiStatus, err = c.broker.getReceiptMessage(nil, servicePair, idx)
 		if err != nil {
 			logger.Error("get receipt message", "servicePair", servicePair, "err", err.Error())
 		}
@@ -630,37 +793,33 @@ func (c *Client) GetReceiptMessage(servicePair string, idx uint64) (*pb.IBTP, er
 		return nil, err
 	}
 
-	return generateReceipt(srcServiceID, dstServiceID, idx, data, typ, encrypt, multiStatus)
+	proof, err := c.eventProof(receiptEventID(srcServiceID, dstServiceID, idx), c.abi.Events["throwReceiptEvent"].ID, types.Log{})
+	if err != nil {
+		return nil, err
+	}
+
+	return generateReceipt(srcServiceID, dstServiceID, idx, data, typ, encrypt, multiStatus, proof)
 }
 
 // GetInMeta queries contract about how many interchain txs have been
 // executed on this appchain for different source chains.
 func (c *Client) GetInMeta() (map[string]uint64, error) {
-	if c.session == nil {
-		return c.getMeta(c.sessionDirect.GetInnerMeta)
-	}
-	return c.getMeta(c.session.GetInnerMeta)
+	return c.getMeta(c.broker.getInnerMeta, nil)
 }
 
 // GetOutMeta queries contract about how many interchain txs have been
 // sent out on this appchain to different destination chains.
 func (c *Client) GetOutMeta() (map[string]uint64, error) {
-	if c.session == nil {
-		return c.getMeta(c.sessionDirect.GetOuterMeta)
-	}
-	return c.getMeta(c.session.GetOuterMeta)
+	return c.getMeta(c.broker.getOuterMeta, nil)
 }
 
 // GetCallbackMeta queries contract about how many callback functions have been
 // executed on this appchain from different destination chains.
 func (c *Client) GetCallbackMeta() (map[string]uint64, error) {
-	if c.session == nil {
-		return c.getMeta(c.sessionDirect.GetCallbackMeta)
-	}
-	return c.getMeta(c.session.GetCallbackMeta)
+	return c.getMeta(c.broker.getCallbackMeta, nil)
 }
 
-func (c *Client) getMeta(getMetaFunc func() ([]string, []uint64, error)) (map[string]uint64, error) {
+func (c *Client) getMeta(getMetaFunc func(*bind.CallOpts) ([]string, []uint64, error), opts *bind.CallOpts) (map[string]uint64, error) {
 	var (
 		appchainIDs []string
 		indices     []uint64
@@ -668,7 +827,7 @@ func (c *Client) getMeta(getMetaFunc func() ([]string, []uint64, error)) (map[st
 	)
 	meta := make(map[string]uint64, 0)
 
-	appchainIDs, indices, err = getMetaFunc()
+	appchainIDs, indices, err = getMetaFunc(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -680,58 +839,131 @@ func (c *Client) getMeta(getMetaFunc func() ([]string, []uint64, error)) (map[st
 	return meta, nil
 }
 
-func (c *Client) getBestBlock() uint64 {
+// getBestBlock returns the chain head known to the head tracker, retrying a
+// few times before the error is left to the caller
+func (c *Client) getBestBlock() (uint64, error) {
 	var blockNum uint64
 
 	if err := retry.Retry(func(attempt uint) error {
 		var err error
-		blockNum, err = c.ethClient.BlockNumber(c.ctx)
+		blockNum, err = c.heads.current()
 		if err != nil {
 			logger.Error("retry failed in getting best block", "err", err.Error())
 		}
 		return err
-	}, strategy.Wait(time.Second*10)); err != nil {
-		logger.Error("retry failed in get best block", "err", err.Error())
-		panic(err)
+	}, strategy.Limit(bestBlockAttempts), strategy.Wait(time.Second*10)); err != nil {
+		return 0, err
 	}
 
-	return blockNum
+	return blockNum, nil
 }
 
-func (c *Client) waitForConfirmed(hash common.Hash) *types.Receipt {
-	var (
-		receipt *types.Receipt
-		err     error
-	)
+// waitForConfirmed waits until tx, or any replacement of it, has been mined
+// for min_confirm blocks. A tx pending for resend_blocks is sped up with
+// bumped fees, and it is given up or cancelled after deadline_blocks.
+func (c *Client) waitForConfirmed(tx *types.Transaction) (*types.Receipt, error) {
+	cfg := c.config.Fee
+	heads, unsubscribe := c.heads.subscribe()
+	defer unsubscribe()
 
-	start := c.getBestBlock()
+	tracked := newTrackedTx(tx)
+	defer c.settleNonce(tracked)
 
-	for c.getBestBlock()-start < c.config.Ether.MinConfirm {
-		time.Sleep(time.Second * 5)
+	start, err := c.getBestBlock()
+	if err != nil {
+		return nil, err
 	}
-	if err := retry.Retry(func(attempt uint) error {
-		receipt, err = c.ethClient.TransactionReceipt(c.ctx, hash)
-		if err != nil {
-			return err
+	tracked.startAt, tracked.sentAt = start, start
+	var cancelled *types.Transaction
+
+	// a stale head still rechecks the tx in case the tracker lost the node
+	ticker := time.NewTicker(headStaleAfter)
+	defer ticker.Stop()
+
+	for head := start; ; {
+		receipt, err := c.minedReceipt(tracked)
+		switch {
+		case err == nil:
+			confirmed, err := c.confirmedHeight(head)
+			if err != nil {
+				logger.Warn("Can't get confirmed height", "policy", c.confirm.String(), "error", err)
+				break
+			}
+			if receipt.BlockNumber.Uint64() > confirmed {
+				break
+			}
+			// the tx may be moved by a reorg while waiting for confirmation
+			if receipt, err = c.minedReceipt(tracked); err != nil {
+				break
+			}
+			c.nonces.confirm(tracked.nonce())
+			tracked.settled = true
+			if cancelled != nil && receipt.TxHash == cancelled.Hash() {
+				return nil, fmt.Errorf("%w: %s", errTxCancelled, tx.Hash().Hex())
+			}
+			if receipt.TxHash != tx.Hash() {
+				logger.Info("Tx mined by replacement", "hash", tx.Hash().Hex(), "replacement", receipt.TxHash.Hex())
+			}
+			return receipt, nil
+		case err == ethereum.NotFound:
+			pending, err := c.inMempool(tracked)
+			if err != nil {
+				logger.Warn("Can't get tx", "hash", tracked.latest().Hash().Hex(), "error", err)
+				break
+			}
+			if !pending {
+				tracked.missing++
+				if tracked.missing >= txDroppedChecks {
+					return nil, c.checkDropped(tracked)
+				}
+				break
+			}
+			tracked.missing = 0
+
+			if cfg.DeadlineBlocks != 0 && head >= tracked.startAt+cfg.DeadlineBlocks {
+				if !cfg.CancelOnDeadline || cancelled != nil {
+					return nil, c.giveUp(tracked, errTxDeadline)
+				}
+				if cancelled, err = c.cancelTx(tracked); err != nil {
+					return nil, c.giveUp(tracked, errTxDeadline)
+				}
+				// wait for the cancel tx for another deadline_blocks
+				tracked.startAt = head
+				break
+			}
+			if cancelled == nil && cfg.ResendBlocks != 0 && head >= tracked.sentAt+cfg.ResendBlocks {
+				if err := c.speedUp(tracked); err != nil {
+					logger.Warn("Can't speed up tx", "hash", tracked.latest().Hash().Hex(), "error", err)
+				}
+				tracked.sentAt = head
+			}
+		default:
+			logger.Warn("Can't get receipt for tx", "hash", tracked.latest().Hash().Hex(), "error", err)
 		}
 
-		return nil
-	}, strategy.Wait(2*time.Second)); err != nil {
-		logger.Error("Can't get receipt for tx", hash.Hex(), "error", err)
+		select {
+		case head = <-heads:
+		case <-ticker.C:
+			if head, err = c.getBestBlock(); err != nil {
+				return nil, err
+			}
+		case <-c.ctx.Done():
+			return nil, c.ctx.Err()
+		}
 	}
-
-	return receipt
 }
 
 func (c *Client) GetDstRollbackMeta() (map[string]uint64, error) {
-	if c.session == nil {
-		return c.getMeta(c.sessionDirect.GetDstRollbackMeta)
-	}
-	return c.getMeta(c.session.GetDstRollbackMeta)
+	return c.getMeta(c.broker.getDstRollbackMeta, nil)
 }
 
 func (c *Client) GetDirectTransactionMeta(IBTPid string) (uint64, uint64, uint64, error) {
-	timestamp, txStatus, err := c.sessionDirect.GetDirectTransactionMeta(IBTPid)
+	direct, ok := c.broker.(directTransactions)
+	if !ok {
+		return 0, 0, 0, unsupported("GetDirectTransactionMeta", c.broker)
+	}
+
+	timestamp, txStatus, err := direct.getDirectTransactionMeta(nil, IBTPid)
 	if err != nil {
 		return 0, 0, 0, err
 	}
@@ -740,21 +972,20 @@ func (c *Client) GetDirectTransactionMeta(IBTPid string) (uint64, uint64, uint64
 }
 
 func (c *Client) GetChainID() (string, string, error) {
-	if c.session == nil {
-		return c.sessionDirect.GetChainID()
-	}
-	return c.session.GetChainID()
+	return c.broker.getChainID(nil)
 }
 
 func (c *Client) GetServices() ([]string, error) {
-	if c.session == nil {
-		return c.sessionDirect.GetLocalServiceList()
-	}
-	return c.session.GetLocalServiceList()
+	return c.broker.getLocalServiceList(nil)
 }
 
 func (c *Client) GetAppchainInfo(chainID string) (string, []byte, string, error) {
-	broker, trustRoot, ruleAddr, err := c.sessionDirect.GetAppchainInfo(chainID)
+	registry, ok := c.broker.(appchainRegistry)
+	if !ok {
+		return "", nil, "", unsupported("GetAppchainInfo", c.broker)
+	}
+
+	broker, trustRoot, ruleAddr, err := registry.getAppchainInfo(nil, chainID)
 	if err != nil {
 		return "", nil, "", err
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -9,40 +10,173 @@ import (
 
 const (
 	configName = "ethereum.toml"
-	directMode = "direct"
-	relayMode  = "relay"
+	// maxBatchSize bounds batch.max_size, as brokers deployed before the
+	// batch loops counted with uint256 overflow a uint8 counter beyond it
+	maxBatchSize = 255
+	directMode   = "direct"
+	relayMode    = "relay"
 )
 
 type Config struct {
-	Ether Ether `toml:"ether" json:"ether"`
+	Ether      Ether      `toml:"ether" json:"ether"`
+	Fee        Fee        `toml:"fee" json:"fee"`
+	Batch      Batch      `toml:"batch" json:"batch"`
+	Simulate   Simulate   `toml:"simulate" json:"simulate"`
+	Gas        Gas        `toml:"gas" json:"gas"`
+	Health     Health     `toml:"health" json:"health"`
+	Validators Validators `toml:"validators" json:"validators"`
 }
 
 type Ether struct {
-	Addr            string `toml:"addr" json:"addr"`
-	Name            string `toml:"name" json:"name"`
-	ContractAddress string `mapstructure:"contract_address" json:"contract_address"`
-	KeyPath         string `mapstructure:"key_path" json:"key_path"`
-	Password        string `toml:"password" json:"password"`
-	MinConfirm      uint64 `mapstructure:"min_confirm" json:"min_confirm"`
-	TimeoutHeight   uint64 `mapstructure:"timeout_height" json:"timeout_height"`
-	TimeoutPeriod   uint64 `mapstructure:"timeout_period" json:"timeout_period"`
-	ChainID         string `mapstructure:"chain_id" json:"chain_id"`
-	OffChainAddr    string `mapstructure:"offchain_addr" json:"offchain_addr"`
-	OffChainPath    string `mapstructure:"offchain_path" json:"offchain_path"`
+	Addr              []string `toml:"addr" json:"addr"`
+	Name              string   `toml:"name" json:"name"`
+	Mode              string   `toml:"mode" json:"mode"`
+	ContractAddress   string   `mapstructure:"contract_address" json:"contract_address"`
+	KeyPath           string   `mapstructure:"key_path" json:"key_path"`
+	Password          string   `toml:"password" json:"password"`
+	MinConfirm        uint64   `mapstructure:"min_confirm" json:"min_confirm"`
+	Confirmation      string   `mapstructure:"confirmation" json:"confirmation"`
+	TimeoutHeight     uint64   `mapstructure:"timeout_height" json:"timeout_height"`
+	TimeoutPeriod     uint64   `mapstructure:"timeout_period" json:"timeout_period"`
+	ChainID           string   `mapstructure:"chain_id" json:"chain_id"`
+	OffChainAddr      string   `mapstructure:"offchain_addr" json:"offchain_addr"`
+	OffChainPath      string   `mapstructure:"offchain_path" json:"offchain_path"`
+	StartHeight       uint64   `mapstructure:"start_height" json:"start_height"`
+	CheckpointPath    string   `mapstructure:"checkpoint_path" json:"checkpoint_path"`
+	EventSource       string   `mapstructure:"event_source" json:"event_source"`
+	PollInterval      uint64   `mapstructure:"poll_interval" json:"poll_interval"`
+	BlockRange        uint64   `mapstructure:"block_range" json:"block_range"`
+	ReconcileInterval uint64   `mapstructure:"reconcile_interval" json:"reconcile_interval"`
+}
+
+// Fee configures the fee strategy of broker transactions, the fees are in wei
+type Fee struct {
+	Strategy          string  `mapstructure:"strategy" json:"strategy"`
+	PriceMultiplier   float64 `mapstructure:"price_multiplier" json:"price_multiplier"`
+	BaseFeeMultiplier float64 `mapstructure:"base_fee_multiplier" json:"base_fee_multiplier"`
+	FeeHistoryBlocks  uint64  `mapstructure:"fee_history_blocks" json:"fee_history_blocks"`
+	RewardPercentile  float64 `mapstructure:"reward_percentile" json:"reward_percentile"`
+	GasPrice          uint64  `mapstructure:"gas_price" json:"gas_price"`
+	FeeCap            uint64  `mapstructure:"fee_cap" json:"fee_cap"`
+	TipCap            uint64  `mapstructure:"tip_cap" json:"tip_cap"`
+	MaxGasPrice       uint64  `mapstructure:"max_gas_price" json:"max_gas_price"`
+	MaxFeeCap         uint64  `mapstructure:"max_fee_cap" json:"max_fee_cap"`
+	MaxTipCap         uint64  `mapstructure:"max_tip_cap" json:"max_tip_cap"`
+	ResendBlocks      uint64  `mapstructure:"resend_blocks" json:"resend_blocks"`
+	BumpPercent       uint64  `mapstructure:"bump_percent" json:"bump_percent"`
+	DeadlineBlocks    uint64  `mapstructure:"deadline_blocks" json:"deadline_blocks"`
+	CancelOnDeadline  bool    `mapstructure:"cancel_on_deadline" json:"cancel_on_deadline"`
+}
+
+// Batch configures the accumulation of SubmitIBTP calls into invokeInterchains
+// txs, a batch is flushed as soon as any of the thresholds is reached
+type Batch struct {
+	Enable   bool   `mapstructure:"enable" json:"enable"`
+	MaxSize  int    `mapstructure:"max_size" json:"max_size"`
+	MaxGas   uint64 `mapstructure:"max_gas" json:"max_gas"`
+	MaxDelay uint64 `mapstructure:"max_delay" json:"max_delay"`
+	ItemGas  uint64 `mapstructure:"item_gas" json:"item_gas"`
+}
+
+// Simulate switches per mode the pre-flight check of broker transactions
+// against the pending state, an IBTP the broker would fail without reverting
+// is never broadcast. A tx bound to revert is rejected by the gas estimation.
+type Simulate struct {
+	Relay  bool `mapstructure:"relay" json:"relay"`
+	Direct bool `mapstructure:"direct" json:"direct"`
+}
+
+func (s Simulate) enabled(mode string) bool {
+	switch mode {
+	case relayMode:
+		return s.Relay
+	case directMode:
+		return s.Direct
+	default:
+		return false
+	}
+}
+
+// Gas configures the gas limit of broker transactions. The method names of
+// the per method tables are lower case, as viper folds the keys.
+type Gas struct {
+	Multiplier float64           `mapstructure:"multiplier" json:"multiplier"`
+	Ceiling    uint64            `mapstructure:"ceiling" json:"ceiling"`
+	Min        map[string]uint64 `mapstructure:"min" json:"min"`
+	Fallback   map[string]uint64 `mapstructure:"fallback" json:"fallback"`
+}
+
+// Health configures the health checks of the endpoints in addr. The client
+// fails over when its endpoint lags behind the others by more than max_lag
+// blocks or more than max_error_rate of the recent checks failed.
+type Health struct {
+	CheckInterval uint64  `mapstructure:"check_interval" json:"check_interval"`
+	MaxLag        uint64  `mapstructure:"max_lag" json:"max_lag"`
+	MaxErrorRate  float64 `mapstructure:"max_error_rate" json:"max_error_rate"`
+}
+
+// Validators locates the validator set pier is configured with, which the
+// validator set of the relay broker is compared to every check_interval.
+// The validators and quorum of pier_config take precedence over the
+// validators file at path, and a non zero threshold over the quorum.
+type Validators struct {
+	Path          string `mapstructure:"path" json:"path"`
+	PierConfig    string `mapstructure:"pier_config" json:"pier_config"`
+	Threshold     uint64 `mapstructure:"threshold" json:"threshold"`
+	CheckInterval uint64 `mapstructure:"check_interval" json:"check_interval"`
 }
 
 func defaultConfig() *Config {
 	return &Config{
 		Ether: Ether{
-			Addr:            "https://mainnet.infura.io",
-			Name:            "Ethereum",
-			ContractAddress: "0xD3880ea40670eD51C3e3C0ea089fDbDc9e3FBBb4",
-			KeyPath:         "account.key",
-			Password:        "",
-			MinConfirm:      15,
-			TimeoutHeight:   100,
-			TimeoutPeriod:   60,
-			OffChainAddr:    "",
+			Addr:              []string{"https://mainnet.infura.io"},
+			Name:              "Ethereum",
+			Mode:              relayMode,
+			ContractAddress:   "0xD3880ea40670eD51C3e3C0ea089fDbDc9e3FBBb4",
+			KeyPath:           "account.key",
+			Password:          "",
+			MinConfirm:        15,
+			TimeoutHeight:     100,
+			TimeoutPeriod:     60,
+			OffChainAddr:      "",
+			CheckpointPath:    "checkpoint",
+			PollInterval:      5,
+			BlockRange:        2000,
+			ReconcileInterval: 60,
+		},
+		Fee: Fee{
+			PriceMultiplier:   1,
+			BaseFeeMultiplier: 2,
+			FeeHistoryBlocks:  10,
+			RewardPercentile:  50,
+			BumpPercent:       20,
+		},
+		Batch: Batch{
+			MaxSize:  20,
+			MaxGas:   8000000,
+			MaxDelay: 500,
+			ItemGas:  200000,
+		},
+		Gas: Gas{
+			Multiplier: 1.2,
+			Min:        map[string]uint64{},
+			Fallback: map[string]uint64{
+				"invokeinterchain":      1000000,
+				"invokemultiinterchain": 3000000,
+				"invokereceipt":         800000,
+				"invokemultireceipt":    1500000,
+				"invokeinterchains":     6000000,
+				"invokereceipts":        6000000,
+			},
+		},
+		Health: Health{
+			CheckInterval: 10,
+			MaxLag:        3,
+			MaxErrorRate:  0.2,
+		},
+		Validators: Validators{
+			Path:          "ether.validators",
+			CheckInterval: 300,
 		},
 	}
 }
@@ -64,5 +198,24 @@ func UnmarshalConfig(configRoot string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
+
+// validate rejects the values the plugin can't run with, like intervals and
+// ranges of 0 which would stall the event loops
+func (c *Config) validate() error {
+	if c.Ether.PollInterval == 0 {
+		return fmt.Errorf("ether.poll_interval must be positive")
+	}
+	if c.Ether.BlockRange == 0 {
+		return fmt.Errorf("ether.block_range must be positive")
+	}
+	if c.Batch.MaxSize < 1 || c.Batch.MaxSize > maxBatchSize {
+		return fmt.Errorf("batch.max_size must be between 1 and %d", maxBatchSize)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "ether-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := ioutil.WriteFile(filepath.Join(dir, configName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestUnmarshalConfigValidates(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "defaults", content: "[ether]\nname = \"ether\"\n"},
+		{name: "zero poll interval", content: "[ether]\npoll_interval = 0\n", wantErr: "poll_interval"},
+		{name: "zero block range", content: "[ether]\nblock_range = 0\n", wantErr: "block_range"},
+		{name: "oversized batch", content: "[batch]\nmax_size = 256\n", wantErr: "max_size"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := UnmarshalConfig(writeConfig(t, test.content))
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("unmarshal config: %v", err)
+			case test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)):
+				t.Fatalf("unmarshal config error %v, want one about %s", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+const (
+	// confirmInterval is how often pending events are checked for confirmation
+	confirmInterval = 5 * time.Second
+	// emittedRetention is how many blocks an emitted event is remembered to
+	// suppress duplicates caused by reorgs
+	emittedRetention = 256
+)
+
+type pendingEvent struct {
+	id   string
+	raw  types.Log
+	emit func() error
+}
+
+type logKey struct {
+	blockHash common.Hash
+	index     uint
+}
+
+func interchainEventID(src, dst string, index uint64) string {
+	return fmt.Sprintf("interchain-%s-%d", pb.GenServicePair(src, dst), index)
+}
+
+func receiptEventID(src, dst string, index uint64) string {
+	return fmt.Sprintf("receipt-%s-%d", pb.GenServicePair(src, dst), index)
+}
+
+// sortPendingEvents orders events the same way they were emitted on chain
+func sortPendingEvents(events []*pendingEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].raw.BlockNumber != events[j].raw.BlockNumber {
+			return events[i].raw.BlockNumber < events[j].raw.BlockNumber
+		}
+		return events[i].raw.Index < events[j].raw.Index
+	})
+}
+
+// confirmQueue holds broker events until they are confirmed by the
+// confirmation policy. Events withdrawn by a reorg are dropped, and an event
+// mined again in another block is emitted only once.
+type confirmQueue struct {
+	// confirmed returns the highest confirmed block for a chain head
+	confirmed func(head uint64) (uint64, error)
+	// instant skips the canonical check of blocks confirmed once mined
+	instant bool
+	head    uint64
+	// scanned is the height up to which the broker events have been pushed
+	// while backfilling, events of later blocks may still be unknown
+	scanned uint64
+	// consumed is the height up to which every event has been emitted
+	consumed uint64
+	// progress is told each time consumed grows
+	progress func(consumed uint64)
+	pending  map[logKey]*pendingEvent
+	emitted  map[string]uint64
+}
+
+func newConfirmQueue(confirmed func(uint64) (uint64, error), instant bool) *confirmQueue {
+	return &confirmQueue{
+		confirmed: confirmed,
+		instant:   instant,
+		scanned:   math.MaxUint64,
+		pending:   make(map[logKey]*pendingEvent),
+		emitted:   make(map[string]uint64),
+	}
+}
+
+func (q *confirmQueue) push(ev *pendingEvent) {
+	key := logKey{blockHash: ev.raw.BlockHash, index: ev.raw.Index}
+	if ev.raw.Removed {
+		if _, ok := q.pending[key]; ok {
+			delete(q.pending, key)
+			logger.Info("Withdraw event removed by reorg", "id", ev.id, "height", ev.raw.BlockNumber, "block", ev.raw.BlockHash.Hex())
+		} else if _, ok := q.emitted[ev.id]; ok {
+			logger.Warn("Event removed by reorg after emitted, consider a stricter confirmation", "id", ev.id, "height", ev.raw.BlockNumber)
+		}
+		return
+	}
+
+	if height, ok := q.emitted[ev.id]; ok {
+		logger.Info("Ignore event emitted already", "id", ev.id, "emitted height", height, "height", ev.raw.BlockNumber)
+		return
+	}
+
+	if ev.raw.BlockNumber > q.head {
+		q.head = ev.raw.BlockNumber
+	}
+	q.pending[key] = ev
+}
+
+// release emits the pending events which are confirmed on the canonical chain.
+// canonicalHash returns the hash of the canonical block at given height. An
+// event failed to emit stays pending with all events after it, so the next
+// release retries from it and no later event gets ahead of it.
+func (q *confirmQueue) release(head uint64, canonicalHash func(uint64) (common.Hash, error)) {
+	if head > q.head {
+		q.head = head
+	}
+
+	height, err := q.confirmed(q.head)
+	if err != nil {
+		logger.Warn("get confirmed height", "head", q.head, "err", err.Error())
+		return
+	}
+
+	var confirmed []*pendingEvent
+	for _, ev := range q.pending {
+		if ev.raw.BlockNumber <= height {
+			confirmed = append(confirmed, ev)
+		}
+	}
+	sortPendingEvents(confirmed)
+
+	hashes := make(map[uint64]common.Hash)
+	for _, ev := range confirmed {
+		key := logKey{blockHash: ev.raw.BlockHash, index: ev.raw.Index}
+		if !q.instant {
+			hash, ok := hashes[ev.raw.BlockNumber]
+			if !ok {
+				var err error
+				hash, err = canonicalHash(ev.raw.BlockNumber)
+				if err != nil {
+					logger.Warn("get canonical block hash", "height", ev.raw.BlockNumber, "err", err.Error())
+					q.consumeBefore(ev.raw.BlockNumber)
+					return
+				}
+				hashes[ev.raw.BlockNumber] = hash
+			}
+			if hash != ev.raw.BlockHash {
+				delete(q.pending, key)
+				logger.Info("Drop event on orphaned block", "id", ev.id, "height", ev.raw.BlockNumber, "block", ev.raw.BlockHash.Hex())
+				continue
+			}
+		}
+
+		if _, ok := q.emitted[ev.id]; ok {
+			delete(q.pending, key)
+			continue
+		}
+		if err := ev.emit(); err != nil {
+			logger.Warn("Emit event, retry later", "id", ev.id, "height", ev.raw.BlockNumber, "err", err.Error())
+			q.consumeBefore(ev.raw.BlockNumber)
+			return
+		}
+		delete(q.pending, key)
+		q.emitted[ev.id] = ev.raw.BlockNumber
+	}
+	q.consume(height)
+
+	for id, emittedAt := range q.emitted {
+		if emittedAt+emittedRetention < height {
+			delete(q.emitted, id)
+		}
+	}
+}
+
+// consume records that every event up to height has been emitted, as far as
+// the blocks have been scanned
+func (q *confirmQueue) consume(height uint64) {
+	if height > q.scanned {
+		height = q.scanned
+	}
+	if height <= q.consumed {
+		return
+	}
+	q.consumed = height
+	if q.progress != nil {
+		q.progress(height)
+	}
+}
+
+// consumeBefore records that every event below height has been emitted
+func (q *confirmQueue) consumeBefore(height uint64) {
+	if height == 0 {
+		return
+	}
+	q.consume(height - 1)
+}
+
+func (c *Client) canonicalHash(height uint64) (common.Hash, error) {
+	header, err := c.ethClient().HeaderByNumber(c.ctx, new(big.Int).SetUint64(height))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Hash(), nil
+}
+
+// releaseConfirmed emits the events confirmed by the current chain head
+func (c *Client) releaseConfirmed(queue *confirmQueue) {
+	head, err := c.heads.current()
+	if err != nil {
+		logger.Warn("get best block", "err", err.Error())
+		return
+	}
+	queue.release(head, c.canonicalHash)
+}
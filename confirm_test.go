@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestConfirmQueueRetriesFailedEvent(t *testing.T) {
+	queue := newConfirmQueue(func(head uint64) (uint64, error) { return head, nil }, true)
+
+	var (
+		emitted []string
+		fail    = true
+	)
+	for i, id := range []string{"first", "second"} {
+		id := id
+		queue.push(&pendingEvent{
+			id:  id,
+			raw: types.Log{BlockNumber: uint64(10 + i), BlockHash: common.HexToHash(id)},
+			emit: func() error {
+				if id == "first" && fail {
+					return errors.New("proof unavailable")
+				}
+				emitted = append(emitted, id)
+				return nil
+			},
+		})
+	}
+
+	queue.release(11, nil)
+	if len(emitted) != 0 {
+		t.Fatalf("emitted %v after the first event failed, want none", emitted)
+	}
+	if len(queue.pending) != 2 {
+		t.Fatalf("%d events pending, want both kept for retry", len(queue.pending))
+	}
+
+	fail = false
+	queue.release(11, nil)
+	if len(emitted) != 2 || emitted[0] != "first" || emitted[1] != "second" {
+		t.Fatalf("emitted %v, want [first second]", emitted)
+	}
+	if len(queue.pending) != 0 {
+		t.Fatalf("%d events still pending", len(queue.pending))
+	}
+}
+
+func TestConfirmQueueConsumedHeight(t *testing.T) {
+	queue := newConfirmQueue(func(head uint64) (uint64, error) { return head, nil }, true)
+	var progress []uint64
+	queue.progress = func(consumed uint64) {
+		progress = append(progress, consumed)
+	}
+
+	fail := true
+	queue.push(&pendingEvent{
+		id:  "retried",
+		raw: types.Log{BlockNumber: 8, BlockHash: common.HexToHash("0x08")},
+		emit: func() error {
+			if fail {
+				return errors.New("proof unavailable")
+			}
+			return nil
+		},
+	})
+
+	// a failed event holds the consumed height below its block
+	queue.release(10, nil)
+	if queue.consumed != 7 {
+		t.Fatalf("consumed %d with the event at 8 failed, want 7", queue.consumed)
+	}
+
+	// blocks not backfilled yet are not consumed
+	fail = false
+	queue.scanned = 9
+	queue.release(10, nil)
+	if queue.consumed != 9 {
+		t.Fatalf("consumed %d with blocks scanned up to 9, want 9", queue.consumed)
+	}
+
+	queue.scanned = math.MaxUint64
+	queue.release(12, nil)
+	if queue.consumed != 12 {
+		t.Fatalf("consumed %d, want the confirmed height 12", queue.consumed)
+	}
+	if len(progress) != 3 || progress[0] != 7 || progress[1] != 9 || progress[2] != 12 {
+		t.Fatalf("progress %v, want [7 9 12]", progress)
+	}
+}
+
+func TestConfirmQueueFailedGenesisEvent(t *testing.T) {
+	queue := newConfirmQueue(func(head uint64) (uint64, error) { return head, nil }, true)
+	queue.push(&pendingEvent{
+		id:   "genesis",
+		raw:  types.Log{BlockNumber: 0, BlockHash: common.HexToHash("0x00")},
+		emit: func() error { return errors.New("proof unavailable") },
+	})
+
+	queue.release(5, nil)
+	if queue.consumed != 0 {
+		t.Fatalf("consumed %d with the event at block 0 failed, want 0", queue.consumed)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	depthConfirm     = "depth"
+	safeConfirm      = "safe"
+	finalizedConfirm = "finalized"
+)
+
+// confirmPolicy decides when a block is final enough for the broker txs and
+// events in it: either a fixed depth below the head, or the block the node
+// tags as safe or finalized
+type confirmPolicy struct {
+	tag   string
+	depth uint64
+}
+
+// parseConfirmPolicy reads "depth:N", "safe" or "finalized", an empty policy
+// falls back to a depth of min_confirm
+func parseConfirmPolicy(s string, minConfirm uint64) (confirmPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "":
+		return confirmPolicy{depth: minConfirm}, nil
+	case safeConfirm, finalizedConfirm:
+		return confirmPolicy{tag: s}, nil
+	}
+
+	if strings.HasPrefix(s, depthConfirm+":") {
+		depth, err := strconv.ParseUint(strings.TrimPrefix(s, depthConfirm+":"), 10, 64)
+		if err != nil {
+			return confirmPolicy{}, fmt.Errorf("invalid confirmation depth %q: %w", s, err)
+		}
+		return confirmPolicy{depth: depth}, nil
+	}
+
+	return confirmPolicy{}, fmt.Errorf("unknown confirmation policy %q", s)
+}
+
+// instant reports whether blocks are confirmed as soon as they are mined
+func (p confirmPolicy) instant() bool {
+	return p.tag == "" && p.depth == 0
+}
+
+func (p confirmPolicy) String() string {
+	if p.tag != "" {
+		return p.tag
+	}
+	return fmt.Sprintf("%s:%d", depthConfirm, p.depth)
+}
+
+// confirmedHeight returns the highest confirmed block when the chain head is
+// at head
+func (c *Client) confirmedHeight(head uint64) (uint64, error) {
+	if c.confirm.tag == "" {
+		if head < c.confirm.depth {
+			return 0, nil
+		}
+		return head - c.confirm.depth, nil
+	}
+	return c.heads.tagged(c.confirm.tag)
+}
+
+// taggedHeight queries the height of the block the node tags with tag
+func (c *Client) taggedHeight(tag string) (uint64, error) {
+	var block struct {
+		Number *hexutil.Uint64 `json:"number"`
+	}
+	if err := c.rpcClient().CallContext(c.ctx, &block, "eth_getBlockByNumber", tag, false); err != nil {
+		return 0, fmt.Errorf("get %s block: %w", tag, err)
+	}
+	if block.Number == nil {
+		return 0, fmt.Errorf("node has no %s block", tag)
+	}
+	return uint64(*block.Number), nil
+}
@@ -1,46 +1,302 @@
-//nolint:dupl
 package main
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/meshplus/bitxhub-model/pb"
 )
 
-func (c *Client) StartConsumer() error {
-	loop := func(interchainCh chan *BrokerThrowInterchainEvent, receiptCh chan *BrokerThrowReceiptEvent) {
+var errSubscriptionClosed = errors.New("subscription closed")
+
+// eventDecoder turns a broker log into the pending event which hands its IBTP
+// to pier. Relay and direct brokers emit the same events, each decoder parses
+// them with its own binding.
+type eventDecoder func(log types.Log) (*pendingEvent, error)
+
+func (c *Client) StartConsumer(session *BrokerSession) error {
+	return c.startConsumer(func(log types.Log) (*pendingEvent, error) {
+		switch log.Topics[0] {
+		case c.abi.Events["throwInterchainEvent"].ID:
+			ev, err := session.Contract.ParseThrowInterchainEvent(log)
+			if err != nil {
+				return nil, err
+			}
+			return c.interchainEvent(ev.SrcFullID, ev.DstFullID, ev.Index, ev.Raw, func() (*pb.IBTP, error) {
+				return c.Convert2IBTP(ev, int64(c.config.Ether.TimeoutHeight))
+			}), nil
+		case c.abi.Events["throwReceiptEvent"].ID:
+			ev, err := session.Contract.ParseThrowReceiptEvent(log)
+			if err != nil {
+				return nil, err
+			}
+			return c.receiptEvent(ev.SrcFullID, ev.DstFullID, ev.Index, ev.Raw, func() (*pb.IBTP, error) {
+				return c.Convert2Receipt(ev)
+			}), nil
+		}
+		return nil, fmt.Errorf("unknown broker event %s", log.Topics[0].Hex())
+	})
+}
+
+// interchainEvent is the pending interchain event of the service pair src and
+// dst. Once confirmed it emits the IBTP built by convert, and the checkpoint
+// only moves past it if convert succeeds, so a failed event is retried by the
+// confirm queue.
+func (c *Client) interchainEvent(src, dst string, index uint64, raw types.Log, convert func() (*pb.IBTP, error)) *pendingEvent {
+	return &pendingEvent{
+		id:  interchainEventID(src, dst, index),
+		raw: raw,
+		emit: func() error {
+			ibtp, err := convert()
+			if err != nil {
+				return fmt.Errorf("convert to IBTP: %w", err)
+			}
+			c.eventC <- ibtp
+			c.outProgress.markEmitted(pb.GenServicePair(src, dst), index)
+			c.saveCheckpoint(raw)
+			return nil
+		},
+	}
+}
+
+// receiptEvent is the pending receipt event of the service pair src and dst,
+// emitted the same way as interchainEvent
+func (c *Client) receiptEvent(src, dst string, index uint64, raw types.Log, convert func() (*pb.IBTP, error)) *pendingEvent {
+	return &pendingEvent{
+		id:  receiptEventID(src, dst, index),
+		raw: raw,
+		emit: func() error {
+			ibtp, err := convert()
+			if err != nil {
+				return fmt.Errorf("convert to IBTP: %w", err)
+			}
+			c.eventC <- ibtp
+			c.saveCheckpoint(raw)
+			return nil
+		},
+	}
+}
+
+// startConsumer watches or polls the broker events, replays the history
+// events since the checkpoint, and emits every event decoded by decode once
+// it is confirmed
+func (c *Client) startConsumer(decode eventDecoder) error {
+	logCh := make(chan types.Log, 1024)
+	subscribe := func() (event.Subscription, error) {
+		if c.pollingMode() {
+			// polling ends on a failover as well, the resubscription then
+			// picks the source of the new endpoint
+			conn := c.node()
+			sub, err := c.pollLogs(func(log types.Log, quit <-chan struct{}) {
+				select {
+				case logCh <- log:
+				case <-quit:
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+			return untilRetired(conn, sub), nil
+		}
+
+		sub, err := (&nodeBackend{client: c}).SubscribeFilterLogs(c.ctx, c.brokerQuery(), logCh)
+		if err != nil {
+			return nil, fmt.Errorf("watch event: %s", err)
+		}
+		return sub, nil
+	}
+
+	// resubscribe restores the subscription on a new connection and
+	// backfills the events emitted since the last known head
+	resubscribe := func(queue *confirmQueue) (event.Subscription, uint64, error) {
+		var (
+			sub  event.Subscription
+			head uint64
+			from = queue.head
+		)
+		if err := c.reconnect(func() error {
+			var err error
+			sub, err = subscribe()
+			if err != nil {
+				return err
+			}
+			head, err = c.heads.refresh()
+			if err != nil {
+				sub.Unsubscribe()
+				return err
+			}
+			// a gap left by a failed backfill would never be filled, as
+			// the watched events up to head are dropped
+			if err := c.backfill(decode, from, head, nil, queue); err != nil {
+				sub.Unsubscribe()
+				from = resumeBackfill(from, queue)
+				return fmt.Errorf("backfill events after reconnect: %w", err)
+			}
+			return nil
+		}); err != nil {
+			return nil, 0, err
+		}
+		return sub, head, nil
+	}
+
+	loop := func(sub event.Subscription, from, head uint64, skip *Checkpoint) {
+		queue := newConfirmQueue(c.confirmedHeight, c.confirm.instant())
+		queue.progress = func(consumed uint64) {
+			atomic.StoreUint64(&c.consumed, consumed)
+		}
+		if !c.catchUp(decode, from, head, skip, queue) {
+			sub.Unsubscribe()
+			return
+		}
+
+		ticker := time.NewTicker(confirmInterval)
+		defer ticker.Stop()
 		for {
+			var subErr error
 			select {
-			case interchainEv := <-interchainCh:
-				ibtp, err := c.Convert2IBTP(interchainEv, int64(c.config.Ether.TimeoutHeight))
-				if err != nil {
-					logger.Warn("convert to IBTP", "src", interchainEv.SrcFullID, "dst", interchainEv.DstFullID, "idx", interchainEv.Index, "err", err.Error())
+			case log := <-logCh:
+				// events up to head have been delivered by backfill
+				if !log.Removed && log.BlockNumber <= head {
 					continue
 				}
-				c.eventC <- ibtp
-			case receiptEv := <-receiptCh:
-				ibtp, err := c.Convert2Receipt(receiptEv)
+				ev, err := decode(log)
 				if err != nil {
-					logger.Warn("convert to IBTP", "src", receiptEv.SrcFullID, "dst", receiptEv.DstFullID, "idx", receiptEv.Index, "err", err.Error())
+					logger.Warn("parse broker event", "tx", log.TxHash.Hex(), "err", err.Error())
 					continue
 				}
-				c.eventC <- ibtp
+				queue.push(ev)
+				queue.release(queue.head, c.canonicalHash)
+			case <-ticker.C:
+				c.releaseConfirmed(queue)
+			case subErr = <-sub.Err():
+				if subErr == nil {
+					subErr = errSubscriptionClosed
+				}
 			case <-c.ctx.Done():
+				sub.Unsubscribe()
+				return
+			}
+
+			if subErr == nil {
+				continue
+			}
+			logger.Warn("Event subscription dropped", "err", subErr.Error())
+			sub.Unsubscribe()
+			var err error
+			sub, head, err = resubscribe(queue)
+			if err != nil {
 				return
 			}
 		}
 	}
 
-	interchainCh := make(chan *BrokerThrowInterchainEvent, 1024)
-	receiptCh := make(chan *BrokerThrowReceiptEvent, 1024)
-	_, err := c.session.Contract.WatchThrowInterchainEvent(nil, interchainCh)
+	sub, err := subscribe()
 	if err != nil {
-		return fmt.Errorf("watch event: %s", err)
+		return err
 	}
-	_, err = c.session.Contract.WatchThrowReceiptEvent(nil, receiptCh)
-	if err != nil {
-		return fmt.Errorf("watch event: %s", err)
+
+	// the head is read after subscribing, so every event is either
+	// covered by backfill or delivered by the watcher
+	var head uint64
+	from, skip := c.backfillStart()
+	if from != 0 {
+		if head, err = c.heads.refresh(); err != nil {
+			sub.Unsubscribe()
+			return err
+		}
+	}
+	go loop(sub, from, head, skip)
+
+	logger.Info("Consumer started", "polling", c.pollingMode(), "backfill from", from, "backfill to", head)
+	return nil
+}
+
+// catchUp backfills the history events between from and head, retrying
+// with backoff from the first block not replayed until it succeeds, as the
+// watched events up to head are dropped. It returns false once the client
+// is stopped.
+func (c *Client) catchUp(decode eventDecoder, from, head uint64, skip *Checkpoint, queue *confirmQueue) bool {
+	backoff := reconnectMinBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.backfill(decode, from, head, skip, queue)
+		if err == nil {
+			return true
+		}
+		from = resumeBackfill(from, queue)
+		logger.Error("Backfill history events, retry", "from", from, "to", head, "attempt", attempt, "err", err.Error())
+
+		select {
+		case <-c.ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+	}
+}
+
+// resumeBackfill returns the height a failed backfill from from continues at
+func resumeBackfill(from uint64, queue *confirmQueue) uint64 {
+	if queue.scanned != math.MaxUint64 && queue.scanned+1 > from {
+		return queue.scanned + 1
+	}
+	return from
+}
+
+// backfill replays the broker events emitted between from and head,
+// skipping those at or before the checkpoint. The blocks not replayed yet are
+// not consumed until backfill succeeds, then the watched events take over.
+func (c *Client) backfill(decode eventDecoder, from, head uint64, skip *Checkpoint, queue *confirmQueue) error {
+	if from == 0 || from > head {
+		queue.scanned = math.MaxUint64
+		return nil
+	}
+	queue.scanned = from - 1
+
+	for start := from; start <= head; start += c.config.Ether.BlockRange {
+		end := start + c.config.Ether.BlockRange - 1
+		if end > head {
+			end = head
+		}
+		query := c.brokerQuery()
+		query.FromBlock = new(big.Int).SetUint64(start)
+		query.ToBlock = new(big.Int).SetUint64(end)
+		logs, err := c.ethClient().FilterLogs(c.ctx, query)
+		if err != nil {
+			return fmt.Errorf("filter broker events from %d to %d: %w", start, end, err)
+		}
+
+		count := 0
+		for _, log := range logs {
+			if skip.Before(log) {
+				continue
+			}
+			ev, err := decode(log)
+			if err != nil {
+				logger.Warn("parse broker event", "tx", log.TxHash.Hex(), "err", err.Error())
+				continue
+			}
+			queue.push(ev)
+			count++
+		}
+		queue.scanned = end
+		select {
+		case <-c.ctx.Done():
+			return nil
+		default:
+			queue.release(head, c.canonicalHash)
+		}
+		logger.Info("Backfill events", "from", start, "to", end, "count", count)
 	}
-	go loop(interchainCh, receiptCh)
 
-	logger.Info("Consumer started")
+	queue.scanned = math.MaxUint64
 	return nil
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+func TestInterchainEventKeepsCheckpointOnFailure(t *testing.T) {
+	store, err := NewCheckpointStore(t.TempDir(), "0x01")
+	if err != nil {
+		t.Fatalf("open checkpoint store: %v", err)
+	}
+	defer store.Close()
+	c := &Client{eventC: make(chan *pb.IBTP, 1), checkpoint: store, outProgress: newOutProgress()}
+
+	convertErr := errors.New("proof unavailable")
+	ev := c.interchainEvent("1356:chain0:0xa", "1356:chain1:0xb", 4, types.Log{BlockNumber: 12, Index: 3}, func() (*pb.IBTP, error) {
+		if convertErr != nil {
+			return nil, convertErr
+		}
+		return &pb.IBTP{Index: 4}, nil
+	})
+
+	if err := ev.emit(); !errors.Is(err, convertErr) {
+		t.Fatalf("emit error %v, want %v", err, convertErr)
+	}
+	if cp := store.Last(); cp != nil {
+		t.Fatalf("checkpoint moved to %+v past the failed event", cp)
+	}
+	if len(c.eventC) != 0 {
+		t.Fatal("IBTP handed to pier although the conversion failed")
+	}
+
+	convertErr = nil
+	if err := ev.emit(); err != nil {
+		t.Fatalf("emit: %v", err)
+	}
+	if cp := store.Last(); cp == nil || cp.Height != 12 || cp.LogIndex != 3 {
+		t.Fatalf("checkpoint %+v, want the emitted event at 12/3", cp)
+	}
+	if ibtp := <-c.eventC; ibtp.Index != 4 {
+		t.Fatalf("IBTP index %d, want 4", ibtp.Index)
+	}
+}
+
+// logsNode fails the first eth_getLogs queries, then answers the logs in the
+// queried block range
+type logsNode struct {
+	fails   int
+	queries []uint64
+	logs    []types.Log
+}
+
+func (n *logsNode) GetLogs(query struct {
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+}) ([]types.Log, error) {
+	n.queries = append(n.queries, uint64(query.FromBlock))
+	if len(n.queries) <= n.fails {
+		return nil, errors.New("query timeout")
+	}
+	logs := []types.Log{}
+	for _, log := range n.logs {
+		if uint64(query.FromBlock) <= log.BlockNumber && log.BlockNumber <= uint64(query.ToBlock) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func TestCatchUpRetriesFailedBackfill(t *testing.T) {
+	node := &logsNode{fails: 1}
+	for _, height := range []uint64{3, 7} {
+		node.logs = append(node.logs, types.Log{
+			BlockNumber: height,
+			BlockHash:   common.BigToHash(new(big.Int).SetUint64(height)),
+			Topics:      []common.Hash{{}},
+			Data:        []byte{},
+		})
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("register fake node: %v", err)
+	}
+	rpcCli := rpc.DialInProc(server)
+	defer rpcCli.Close()
+
+	brokerABI, err := abi.JSON(strings.NewReader(BrokerABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig()
+	config.Ether.BlockRange = 5
+	c := &Client{ctx: context.Background(), config: config, abi: brokerABI}
+	c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+
+	var emitted []uint64
+	decode := func(log types.Log) (*pendingEvent, error) {
+		return &pendingEvent{
+			id:  fmt.Sprintf("event-%d", log.BlockNumber),
+			raw: log,
+			emit: func() error {
+				emitted = append(emitted, log.BlockNumber)
+				return nil
+			},
+		}, nil
+	}
+	queue := newConfirmQueue(func(head uint64) (uint64, error) { return head, nil }, true)
+
+	if !c.catchUp(decode, 1, 10, nil, queue) {
+		t.Fatal("catch up gave up")
+	}
+	if len(emitted) != 2 || emitted[0] != 3 || emitted[1] != 7 {
+		t.Fatalf("emitted events at %v, want [3 7]", emitted)
+	}
+	if len(node.queries) != 3 || node.queries[0] != 1 || node.queries[1] != 1 || node.queries[2] != 6 {
+		t.Fatalf("queried from %v, want the failed range again before the next one", node.queries)
+	}
+	if queue.consumed != 10 {
+		t.Fatalf("consumed %d, want 10", queue.consumed)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// brokerContract is the broker contract as the client uses it, with one
+// implementation per broker flavor. Features only some flavors offer are
+// the optional interfaces below, checked with a type assertion.
+type brokerContract interface {
+	mode() string
+	abiJSON() string
+	transactOpts() bind.TransactOpts
+	// consume starts pushing the broker events of this flavor to pier
+	consume(c *Client) error
+	// outMessage rebuilds the ibtp of an interchain event from the contract
+	outMessage(c *Client, srcFullID string, dstFullID string, idx uint64) (*pb.IBTP, error)
+
+	invokeInterchain(opts *bind.TransactOpts, srcFullID string, destAddr string, index uint64, typ uint64, callFunc string, args [][]byte, txStatus uint64, signatures [][]byte, isEncrypt bool) (*types.Transaction, error)
+	invokeMultiInterchain(opts *bind.TransactOpts, srcFullID string, destAddr string, index uint64, typ uint64, callFunc string, args [][][]byte, txStatus uint64, signatures [][]byte, isEncrypt bool) (*types.Transaction, error)
+	invokeReceipt(opts *bind.TransactOpts, srcAddr string, dstFullID string, index uint64, typ uint64, results [][][]byte, txStatus uint64, signatures [][]byte) (*types.Transaction, error)
+	invokeMultiReceipt(opts *bind.TransactOpts, srcAddr string, dstFullID string, index uint64, typ uint64, results [][][]byte, multiStatus []bool, txStatus uint64, signatures [][]byte) (*types.Transaction, error)
+
+	getOutMessage(opts *bind.CallOpts, outServicePair string, idx uint64) (string, [][]byte, bool, []string, error)
+	getReceiptMessage(opts *bind.CallOpts, inServicePair string, idx uint64) ([][][]byte, uint64, bool, []bool, error)
+	getOuterMeta(opts *bind.CallOpts) ([]string, []uint64, error)
+	getInnerMeta(opts *bind.CallOpts) ([]string, []uint64, error)
+	getCallbackMeta(opts *bind.CallOpts) ([]string, []uint64, error)
+	getDstRollbackMeta(opts *bind.CallOpts) ([]string, []uint64, error)
+	getChainID(opts *bind.CallOpts) (string, string, error)
+	getLocalServiceList(opts *bind.CallOpts) ([]string, error)
+}
+
+// batchInvoker is a broker that executes many ibtps or receipts in one tx
+// and reports the status of each of them in the tx logs
+type batchInvoker interface {
+	invokeInterchains(opts *bind.TransactOpts, srcFullID []string, destAddr []string, index []uint64, typ []uint64, callFunc []string, args [][][]byte, txStatus []uint64, signatures [][][]byte, isEncrypt []bool) (*types.Transaction, error)
+	invokeReceipts(opts *bind.TransactOpts, srcAddr []string, dstFullID []string, index []uint64, typ []uint64, results [][][][]byte, multiStatus [][]bool, txStatus []uint64, signatures [][][]byte) (*types.Transaction, error)
+	interchainBatchStatus(receipt *types.Receipt, items []*batchItem)
+	receiptBatchStatus(receipt *types.Receipt, items []*batchItem)
+}
+
+// directTransactions is a broker that keeps the meta of direct mode
+// transactions
+type directTransactions interface {
+	getDirectTransactionMeta(opts *bind.CallOpts, id string) (*big.Int, uint64, error)
+}
+
+// appchainRegistry is a broker that keeps the info of other appchains
+type appchainRegistry interface {
+	getAppchainInfo(opts *bind.CallOpts, chainID string) (string, []byte, common.Address, error)
+}
+
+// validatorSet is a broker that checks the multi-signatures of bitxhub
+// against its validators
+type validatorSet interface {
+	getValidators(opts *bind.CallOpts) ([]common.Address, uint64, error)
+}
+
+// whiteList is a broker that only invokes the local services on its white
+// list, and records the interchain calls to other ones as failed instead of
+// reverting
+type whiteList interface {
+	isWhiteListed(opts *bind.CallOpts, addr common.Address) (bool, error)
+}
+
+var (
+	_ brokerContract     = (*relayBroker)(nil)
+	_ batchInvoker       = (*relayBroker)(nil)
+	_ validatorSet       = (*relayBroker)(nil)
+	_ whiteList          = (*relayBroker)(nil)
+	_ brokerContract     = (*directBroker)(nil)
+	_ directTransactions = (*directBroker)(nil)
+	_ appchainRegistry   = (*directBroker)(nil)
+)
+
+// unsupported is returned for calls the broker of the current mode can't serve
+func unsupported(call string, b brokerContract) error {
+	return fmt.Errorf("%s is unsupported in %s mode", call, b.mode())
+}
+
+// relayBroker is the broker of relay mode
+type relayBroker struct {
+	session *BrokerSession
+	abi     abi.ABI
+}
+
+func newRelayBroker(address common.Address, backend bind.ContractBackend, auth *bind.TransactOpts) (*relayBroker, error) {
+	broker, err := NewBroker(address, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate a Broker contract: %w", err)
+	}
+	ab, err := abi.JSON(strings.NewReader(BrokerABI))
+	if err != nil {
+		return nil, fmt.Errorf("abi unmarshal: %w", err)
+	}
+
+	return &relayBroker{
+		session: &BrokerSession{
+			Contract: broker,
+			CallOpts: bind.CallOpts{
+				Pending: false,
+			},
+			TransactOpts: *auth,
+		},
+		abi: ab,
+	}, nil
+}
+
+func (b *relayBroker) mode() string {
+	return relayMode
+}
+
+func (b *relayBroker) abiJSON() string {
+	return BrokerABI
+}
+
+func (b *relayBroker) transactOpts() bind.TransactOpts {
+	return b.session.TransactOpts
+}
+
+func (b *relayBroker) consume(c *Client) error {
+	return c.StartConsumer(b.session)
+}
+
+func (b *relayBroker) outMessage(c *Client, srcFullID string, dstFullID string, idx uint64) (*pb.IBTP, error) {
+	ev := &BrokerThrowInterchainEvent{
+		Index:     idx,
+		DstFullID: dstFullID,
+		SrcFullID: srcFullID,
+	}
+
+	return c.Convert2IBTP(ev, int64(c.config.Ether.TimeoutHeight))
+}
+
+func (b *relayBroker) invokeInterchain(opts *bind.TransactOpts, srcFullID string, destAddr string, index uint64, typ uint64, callFunc string, args [][]byte, txStatus uint64, signatures [][]byte, isEncrypt bool) (*types.Transaction, error) {
+	return b.session.Contract.InvokeInterchain(opts, srcFullID, destAddr, index, typ, callFunc, args, txStatus, signatures, isEncrypt)
+}
+
+func (b *relayBroker) invokeMultiInterchain(opts *bind.TransactOpts, srcFullID string, destAddr string, index uint64, typ uint64, callFunc string, args [][][]byte, txStatus uint64, signatures [][]byte, isEncrypt bool) (*types.Transaction, error) {
+	return b.session.Contract.InvokeMultiInterchain(opts, srcFullID, destAddr, index, typ, callFunc, args, txStatus, signatures, isEncrypt)
+}
+
+func (b *relayBroker) invokeReceipt(opts *bind.TransactOpts, srcAddr string, dstFullID string, index uint64, typ uint64, results [][][]byte, txStatus uint64, signatures [][]byte) (*types.Transaction, error) {
+	return b.session.Contract.InvokeReceipt(opts, srcAddr, dstFullID, index, typ, results, txStatus, signatures)
+}
+
+func (b *relayBroker) invokeMultiReceipt(opts *bind.TransactOpts, srcAddr string, dstFullID string, index uint64, typ uint64, results [][][]byte, multiStatus []bool, txStatus uint64, signatures [][]byte) (*types.Transaction, error) {
+	return b.session.Contract.InvokeMultiReceipt(opts, srcAddr, dstFullID, index, typ, results, multiStatus, txStatus, signatures)
+}
+
+func (b *relayBroker) invokeInterchains(opts *bind.TransactOpts, srcFullID []string, destAddr []string, index []uint64, typ []uint64, callFunc []string, args [][][]byte, txStatus []uint64, signatures [][][]byte, isEncrypt []bool) (*types.Transaction, error) {
+	return b.session.Contract.InvokeInterchains(opts, srcFullID, destAddr, index, typ, callFunc, args, txStatus, signatures, isEncrypt)
+}
+
+func (b *relayBroker) invokeReceipts(opts *bind.TransactOpts, srcAddr []string, dstFullID []string, index []uint64, typ []uint64, results [][][][]byte, multiStatus [][]bool, txStatus []uint64, signatures [][][]byte) (*types.Transaction, error) {
+	return b.session.Contract.InvokeReceipts(opts, srcAddr, dstFullID, index, typ, results, multiStatus, txStatus, signatures)
+}
+
+func (b *relayBroker) getOutMessage(opts *bind.CallOpts, outServicePair string, idx uint64) (string, [][]byte, bool, []string, error) {
+	return b.session.Contract.GetOutMessage(opts, outServicePair, idx)
+}
+
+func (b *relayBroker) getReceiptMessage(opts *bind.CallOpts, inServicePair string, idx uint64) ([][][]byte, uint64, bool, []bool, error) {
+	return b.session.Contract.GetReceiptMessage(opts, inServicePair, idx)
+}
+
+func (b *relayBroker) getOuterMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetOuterMeta(opts)
+}
+
+func (b *relayBroker) getInnerMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetInnerMeta(opts)
+}
+
+func (b *relayBroker) getCallbackMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetCallbackMeta(opts)
+}
+
+func (b *relayBroker) getDstRollbackMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetDstRollbackMeta(opts)
+}
+
+func (b *relayBroker) getChainID(opts *bind.CallOpts) (string, string, error) {
+	return b.session.Contract.GetChainID(opts)
+}
+
+func (b *relayBroker) getLocalServiceList(opts *bind.CallOpts) ([]string, error) {
+	return b.session.Contract.GetLocalServiceList(opts)
+}
+
+// getValidators reads the validators array entry by entry, as its getter
+// takes an index, until the index is out of bounds
+func (b *relayBroker) getValidators(opts *bind.CallOpts) ([]common.Address, uint64, error) {
+	threshold, err := b.session.Contract.ValThreshold(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var validators []common.Address
+	for i := int64(0); ; i++ {
+		addr, err := b.session.Contract.Validators(opts, big.NewInt(i))
+		if isCallReverted(err) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		validators = append(validators, addr)
+	}
+	return validators, threshold, nil
+}
+
+func (b *relayBroker) isWhiteListed(opts *bind.CallOpts, addr common.Address) (bool, error) {
+	return b.session.Contract.GetLocalWhiteList(opts, addr)
+}
+
+// directBroker is the broker of direct mode
+type directBroker struct {
+	session *BrokerDirectSession
+}
+
+func newDirectBroker(address common.Address, backend bind.ContractBackend, auth *bind.TransactOpts) (*directBroker, error) {
+	broker, err := NewBrokerDirect(address, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate a Broker contract: %w", err)
+	}
+
+	return &directBroker{
+		session: &BrokerDirectSession{
+			Contract: broker,
+			CallOpts: bind.CallOpts{
+				Pending: false,
+			},
+			TransactOpts: *auth,
+		},
+	}, nil
+}
+
+func (b *directBroker) mode() string {
+	return directMode
+}
+
+func (b *directBroker) abiJSON() string {
+	return BrokerDirectABI
+}
+
+func (b *directBroker) transactOpts() bind.TransactOpts {
+	return b.session.TransactOpts
+}
+
+func (b *directBroker) consume(c *Client) error {
+	return c.StartDirectConsumer(b.session)
+}
+
+func (b *directBroker) outMessage(c *Client, srcFullID string, dstFullID string, idx uint64) (*pb.IBTP, error) {
+	ev := &BrokerDirectThrowInterchainEvent{
+		Index:     idx,
+		DstFullID: dstFullID,
+		SrcFullID: srcFullID,
+	}
+
+	return c.Convert2DirectIBTP(ev, int64(c.config.Ether.TimeoutHeight))
+}
+
+func (b *directBroker) invokeInterchain(opts *bind.TransactOpts, srcFullID string, destAddr string, index uint64, typ uint64, callFunc string, args [][]byte, txStatus uint64, signatures [][]byte, isEncrypt bool) (*types.Transaction, error) {
+	return b.session.Contract.InvokeInterchain(opts, srcFullID, destAddr, index, typ, callFunc, args, txStatus, signatures, isEncrypt)
+}
+
+func (b *directBroker) invokeMultiInterchain(opts *bind.TransactOpts, srcFullID string, destAddr string, index uint64, typ uint64, callFunc string, args [][][]byte, txStatus uint64, signatures [][]byte, isEncrypt bool) (*types.Transaction, error) {
+	return b.session.Contract.InvokeMultiInterchain(opts, srcFullID, destAddr, index, typ, callFunc, args, txStatus, signatures, isEncrypt)
+}
+
+func (b *directBroker) invokeReceipt(opts *bind.TransactOpts, srcAddr string, dstFullID string, index uint64, typ uint64, results [][][]byte, txStatus uint64, signatures [][]byte) (*types.Transaction, error) {
+	return b.session.Contract.InvokeReceipt(opts, srcAddr, dstFullID, index, typ, results, txStatus, signatures)
+}
+
+func (b *directBroker) invokeMultiReceipt(opts *bind.TransactOpts, srcAddr string, dstFullID string, index uint64, typ uint64, results [][][]byte, multiStatus []bool, txStatus uint64, signatures [][]byte) (*types.Transaction, error) {
+	return b.session.Contract.InvokeMultiReceipt(opts, srcAddr, dstFullID, index, typ, results, multiStatus, txStatus, signatures)
+}
+
+func (b *directBroker) getOutMessage(opts *bind.CallOpts, outServicePair string, idx uint64) (string, [][]byte, bool, []string, error) {
+	return b.session.Contract.GetOutMessage(opts, outServicePair, idx)
+}
+
+func (b *directBroker) getReceiptMessage(opts *bind.CallOpts, inServicePair string, idx uint64) ([][][]byte, uint64, bool, []bool, error) {
+	return b.session.Contract.GetReceiptMessage(opts, inServicePair, idx)
+}
+
+func (b *directBroker) getOuterMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetOuterMeta(opts)
+}
+
+func (b *directBroker) getInnerMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetInnerMeta(opts)
+}
+
+func (b *directBroker) getCallbackMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetCallbackMeta(opts)
+}
+
+func (b *directBroker) getDstRollbackMeta(opts *bind.CallOpts) ([]string, []uint64, error) {
+	return b.session.Contract.GetDstRollbackMeta(opts)
+}
+
+func (b *directBroker) getChainID(opts *bind.CallOpts) (string, string, error) {
+	return b.session.Contract.GetChainID(opts)
+}
+
+func (b *directBroker) getLocalServiceList(opts *bind.CallOpts) ([]string, error) {
+	return b.session.Contract.GetLocalServiceList(opts)
+}
+
+func (b *directBroker) getDirectTransactionMeta(opts *bind.CallOpts, id string) (*big.Int, uint64, error) {
+	return b.session.Contract.GetDirectTransactionMeta(opts, id)
+}
+
+func (b *directBroker) getAppchainInfo(opts *bind.CallOpts, chainID string) (string, []byte, common.Address, error) {
+	return b.session.Contract.GetAppchainInfo(opts, chainID)
+}
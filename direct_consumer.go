@@ -1,44 +1,32 @@
-//nolint:dupl
 package main
 
-import "fmt"
+import (
+	"fmt"
 
-func (c *Client) StartDirectConsumer() error {
-	loop := func(interchainCh chan *BrokerDirectThrowInterchainEvent, receiptCh chan *BrokerDirectThrowReceiptEvent) {
-		for {
-			select {
-			case interchainEv := <-interchainCh:
-				ibtp, err := c.Convert2DirectIBTP(interchainEv, int64(c.config.Ether.TimeoutHeight))
-				if err != nil {
-					logger.Warn("convert to IBTP", "src", interchainEv.SrcFullID, "dst", interchainEv.DstFullID, "idx", interchainEv.Index, "err", err.Error())
-					continue
-				}
-				c.eventC <- ibtp
-			case receiptEv := <-receiptCh:
-				ibtp, err := c.Convert2DirectReceipt(receiptEv)
-				if err != nil {
-					logger.Warn("convert to IBTP", "src", receiptEv.SrcFullID, "dst", receiptEv.DstFullID, "idx", receiptEv.Index, "err", err.Error())
-					continue
-				}
-				c.eventC <- ibtp
-			case <-c.ctx.Done():
-				return
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+func (c *Client) StartDirectConsumer(session *BrokerDirectSession) error {
+	return c.startConsumer(func(log types.Log) (*pendingEvent, error) {
+		switch log.Topics[0] {
+		case c.abi.Events["throwInterchainEvent"].ID:
+			ev, err := session.Contract.ParseThrowInterchainEvent(log)
+			if err != nil {
+				return nil, err
+			}
+			return c.interchainEvent(ev.SrcFullID, ev.DstFullID, ev.Index, ev.Raw, func() (*pb.IBTP, error) {
+				return c.Convert2DirectIBTP(ev, int64(c.config.Ether.TimeoutHeight))
+			}), nil
+		case c.abi.Events["throwReceiptEvent"].ID:
+			ev, err := session.Contract.ParseThrowReceiptEvent(log)
+			if err != nil {
+				return nil, err
 			}
+			return c.receiptEvent(ev.SrcFullID, ev.DstFullID, ev.Index, ev.Raw, func() (*pb.IBTP, error) {
+				return c.Convert2DirectReceipt(ev)
+			}), nil
 		}
-	}
-
-	interchainCh := make(chan *BrokerDirectThrowInterchainEvent, 1024)
-	receiptCh := make(chan *BrokerDirectThrowReceiptEvent, 1024)
-	_, err := c.sessionDirect.Contract.WatchThrowInterchainEvent(nil, interchainCh)
-	if err != nil {
-		return fmt.Errorf("watch event: %s", err)
-	}
-	_, err = c.sessionDirect.Contract.WatchThrowReceiptEvent(nil, receiptCh)
-	if err != nil {
-		return fmt.Errorf("watch event: %s", err)
-	}
-	go loop(interchainCh, receiptCh)
-
-	logger.Info("Consumer started")
-	return nil
+		return nil, fmt.Errorf("unknown broker event %s", log.Topics[0].Hex())
+	})
 }
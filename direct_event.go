@@ -17,6 +17,10 @@ func (c *Client) Convert2DirectIBTP(ev *BrokerDirectThrowInterchainEvent, timeou
 	if err != nil {
 		return nil, err
 	}
+	proof, err := c.eventProof(interchainEventID(ev.SrcFullID, ev.DstFullID, ev.Index), c.abi.Events["throwInterchainEvent"].ID, ev.Raw)
+	if err != nil {
+		return nil, err
+	}
 
 	return &pb.IBTP{
 		From:          ev.SrcFullID,
@@ -24,7 +28,7 @@ func (c *Client) Convert2DirectIBTP(ev *BrokerDirectThrowInterchainEvent, timeou
 		Index:         ev.Index,
 		Type:          pb.IBTP_INTERCHAIN,
 		TimeoutHeight: timeoutHeight,
-		Proof:         []byte("1"),
+		Proof:         proof,
 		Payload:       pd,
 	}, nil
 }
@@ -34,8 +38,12 @@ func (c *Client) Convert2DirectReceipt(ev *BrokerDirectThrowReceiptEvent) (*pb.I
 	if err != nil {
 		return nil, err
 	}
+	proof, err := c.eventProof(receiptEventID(ev.SrcFullID, ev.DstFullID, ev.Index), c.abi.Events["throwReceiptEvent"].ID, ev.Raw)
+	if err != nil {
+		return nil, err
+	}
 
-	return generateReceipt(fullEv.SrcFullID, fullEv.DstFullID, fullEv.Index, fullEv.Results, fullEv.Typ, encrypt, fullEv.MultiStatus)
+	return generateReceipt(fullEv.SrcFullID, fullEv.DstFullID, fullEv.Index, fullEv.Results, fullEv.Typ, encrypt, fullEv.MultiStatus, proof)
 }
 
 func encodeDirectPayload(ev *BrokerDirectThrowInterchainEvent, encrypt bool) ([]byte, error) {
@@ -63,7 +71,7 @@ func encodeDirectPayload(ev *BrokerDirectThrowInterchainEvent, encrypt bool) ([]
 
 func (c *Client) fillDirectInterchainEvent(ev *BrokerDirectThrowInterchainEvent) (*BrokerDirectThrowInterchainEvent, bool, error) {
 	if ev.Func == "" {
-		fun, args, encrypt, _, err := c.sessionDirect.GetOutMessage(pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
+		fun, args, encrypt, _, err := c.broker.getOutMessage(nil, pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
 		if err != nil {
 			return nil, false, err
 		}
@@ -90,7 +98,7 @@ func (c *Client) fillDirectInterchainEvent(ev *BrokerDirectThrowInterchainEvent)
 
 func (c *Client) fillDirectReceiptEvent(ev *BrokerDirectThrowReceiptEvent) (*BrokerDirectThrowReceiptEvent, bool, error) {
 	if ev.Results == nil {
-		results, typ, encrypt, multiStatus, err := c.sessionDirect.GetReceiptMessage(pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
+		results, typ, encrypt, multiStatus, err := c.broker.getReceiptMessage(nil, pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
 		if err != nil {
 			return nil, false, err
 		}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// endpointWindow is how many recent probes the error rate of an endpoint
+	// is measured over
+	endpointWindow = 20
+	// probeTimeout bounds the dial and head query of a single probe
+	probeTimeout = 5 * time.Second
+)
+
+var (
+	errNoEndpoint       = errors.New("no ethereum endpoint available")
+	errConnectionLost   = errors.New("connection lost")
+	errEndpointSwitched = errors.New("switched to another ethereum endpoint")
+)
+
+// endpoint is one ethereum node of addr. Its connection is dialed on the
+// first probe and kept, as the rpc client reconnects a broken websocket by
+// itself. It stays open when the client fails over to another endpoint, so
+// the calls in flight on it complete and the probes go on.
+type endpoint struct {
+	addr   string
+	lock   sync.Mutex
+	rpc    *rpc.Client
+	eth    *ethclient.Client
+	head   uint64
+	ok     bool
+	probes []bool
+}
+
+// endpointHealth is a snapshot of the health of an endpoint
+type endpointHealth struct {
+	endpoint  *endpoint
+	ok        bool
+	head      uint64
+	errorRate float64
+}
+
+func (e *endpoint) clients() (*rpc.Client, *ethclient.Client) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.rpc, e.eth
+}
+
+// probe dials the endpoint if needed and reads its head
+func (e *endpoint) probe(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	// the lock is only held to read and store the state, a slow node must
+	// not block the callers looking at the other endpoints
+	e.lock.Lock()
+	eth := e.eth
+	e.lock.Unlock()
+
+	if eth == nil {
+		rpcCli, err := rpc.DialContext(ctx, e.addr)
+		if err != nil {
+			e.fail(fmt.Errorf("dial ethereum node: %w", err))
+			return
+		}
+		e.lock.Lock()
+		if e.rpc == nil {
+			e.rpc, e.eth = rpcCli, ethclient.NewClient(rpcCli)
+		} else {
+			// dialed by a concurrent probe in the meantime
+			rpcCli.Close()
+		}
+		eth = e.eth
+		e.lock.Unlock()
+	}
+
+	head, err := eth.BlockNumber(ctx)
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if err == nil {
+		e.head = head
+	}
+	e.record(err)
+}
+
+// fail counts an error of the endpoint seen outside the probes
+func (e *endpoint) fail(err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.record(err)
+}
+
+func (e *endpoint) record(err error) {
+	e.ok = err == nil
+	e.probes = append(e.probes, e.ok)
+	if len(e.probes) > endpointWindow {
+		e.probes = e.probes[len(e.probes)-endpointWindow:]
+	}
+	if err != nil {
+		logger.Warn("Ethereum endpoint unhealthy", "addr", e.addr, "error", err.Error())
+	}
+}
+
+func (e *endpoint) health() endpointHealth {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var failed int
+	for _, ok := range e.probes {
+		if !ok {
+			failed++
+		}
+	}
+	h := endpointHealth{endpoint: e, ok: e.ok && e.rpc != nil, head: e.head}
+	if len(e.probes) != 0 {
+		h.errorRate = float64(failed) / float64(len(e.probes))
+	}
+	return h
+}
+
+// endpointPool holds the ethereum endpoints of addr and the active one the
+// client talks to
+type endpointPool struct {
+	cfg       Health
+	endpoints []*endpoint
+	lock      sync.Mutex
+	active    *endpoint
+}
+
+func newEndpointPool(addrs []string, cfg Health) (*endpointPool, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no ethereum node addr configured")
+	}
+	pool := &endpointPool{cfg: cfg}
+	for _, addr := range addrs {
+		pool.endpoints = append(pool.endpoints, &endpoint{addr: addr})
+	}
+	return pool, nil
+}
+
+func (p *endpointPool) current() *endpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.active == nil {
+		return p.endpoints[0]
+	}
+	return p.active
+}
+
+func (p *endpointPool) setCurrent(e *endpoint) *endpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	prev := p.active
+	p.active = e
+	return prev
+}
+
+func (p *endpointPool) probeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			e.probe(ctx)
+		}(e)
+	}
+	wg.Wait()
+}
+
+// best picks the endpoint to use from the last probes. The active endpoint
+// is kept while it is healthy, otherwise the healthy endpoint with the
+// highest head and the lowest error rate wins. An endpoint is healthy when it
+// answers, lags at most max_lag blocks and errs at most max_error_rate. If no
+// endpoint is healthy any answering one is used.
+func (p *endpointPool) best() *endpoint {
+	var (
+		states  []endpointHealth
+		maxHead uint64
+	)
+	for _, e := range p.endpoints {
+		h := e.health()
+		if !h.ok {
+			continue
+		}
+		if h.head > maxHead {
+			maxHead = h.head
+		}
+		states = append(states, h)
+	}
+	if len(states) == 0 {
+		return nil
+	}
+
+	healthy := func(h endpointHealth) bool {
+		return h.head+p.cfg.MaxLag >= maxHead && h.errorRate <= p.cfg.MaxErrorRate
+	}
+	better := func(a, b endpointHealth) bool {
+		if a.head != b.head {
+			return a.head > b.head
+		}
+		return a.errorRate < b.errorRate
+	}
+
+	active := p.current()
+	var best *endpointHealth
+	for i, h := range states {
+		if !healthy(h) {
+			continue
+		}
+		if h.endpoint == active {
+			return active
+		}
+		if best == nil || better(h, *best) {
+			best = &states[i]
+		}
+	}
+	if best == nil {
+		for i, h := range states {
+			if best == nil || better(h, *best) {
+				best = &states[i]
+			}
+		}
+	}
+	return best.endpoint
+}
+
+// nodeConn is the connection of the client to its active endpoint. retired
+// is closed once the client switched to another endpoint, which ends the
+// subscriptions made on the connection so they are made again on the new one.
+type nodeConn struct {
+	endpoint *endpoint
+	rpc      *rpc.Client
+	eth      *ethclient.Client
+	retired  chan struct{}
+}
+
+func newNodeConn(e *endpoint, rpcCli *rpc.Client, etherCli *ethclient.Client) *nodeConn {
+	return &nodeConn{
+		endpoint: e,
+		rpc:      rpcCli,
+		eth:      etherCli,
+		retired:  make(chan struct{}),
+	}
+}
+
+// node returns the connection to the active endpoint, callers read it once
+// per call and never keep it across a failover
+func (c *Client) node() *nodeConn {
+	return c.conn.Load().(*nodeConn)
+}
+
+func (c *Client) ethClient() *ethclient.Client {
+	return c.node().eth
+}
+
+func (c *Client) rpcClient() *rpc.Client {
+	return c.node().rpc
+}
+
+// useEndpoint moves the client onto e. The previous connection is retired,
+// the subscriptions on it fail and are made again on e.
+func (c *Client) useEndpoint(e *endpoint) error {
+	rpcCli, etherCli := e.clients()
+	if rpcCli == nil {
+		return fmt.Errorf("%w: %s is not connected", errNoEndpoint, e.addr)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	prev, _ := c.conn.Load().(*nodeConn)
+	if prev != nil && prev.endpoint == e && prev.rpc == rpcCli {
+		return nil
+	}
+	c.conn.Store(newNodeConn(e, rpcCli, etherCli))
+	c.endpoints.setCurrent(e)
+	if prev != nil {
+		if prev.endpoint != e {
+			logger.Warn("Switch ethereum endpoint", "from", prev.endpoint.addr, "to", e.addr)
+		}
+		close(prev.retired)
+	}
+	return nil
+}
+
+// answeringClients returns the client of the active endpoint, followed by
+// those of the other endpoints whose last probe succeeded
+func (c *Client) answeringClients() []*ethclient.Client {
+	conn := c.node()
+	clients := []*ethclient.Client{conn.eth}
+	if c.endpoints == nil {
+		return clients
+	}
+	for _, e := range c.endpoints.endpoints {
+		if e == conn.endpoint || !e.health().ok {
+			continue
+		}
+		_, eth := e.clients()
+		clients = append(clients, eth)
+	}
+	return clients
+}
+
+// untilRetired ends sub with errEndpointSwitched once conn is retired
+func untilRetired(conn *nodeConn, sub event.Subscription) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		select {
+		case err := <-sub.Err():
+			if err == nil {
+				err = errSubscriptionClosed
+			}
+			return err
+		case <-conn.retired:
+			return errEndpointSwitched
+		case <-quit:
+			return nil
+		}
+	})
+}
+
+// nodeBackend is the contract backend of the broker bindings, every call goes
+// to the endpoint active at that time
+type nodeBackend struct {
+	client *Client
+}
+
+var (
+	_ bind.ContractBackend       = (*nodeBackend)(nil)
+	_ bind.PendingContractCaller = (*nodeBackend)(nil)
+)
+
+func (b *nodeBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return b.client.ethClient().CodeAt(ctx, contract, blockNumber)
+}
+
+func (b *nodeBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return b.client.ethClient().CallContract(ctx, call, blockNumber)
+}
+
+func (b *nodeBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return b.client.ethClient().PendingCodeAt(ctx, account)
+}
+
+func (b *nodeBackend) PendingCallContract(ctx context.Context, call ethereum.CallMsg) ([]byte, error) {
+	return b.client.ethClient().PendingCallContract(ctx, call)
+}
+
+func (b *nodeBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return b.client.ethClient().HeaderByNumber(ctx, number)
+}
+
+func (b *nodeBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return b.client.ethClient().PendingNonceAt(ctx, account)
+}
+
+func (b *nodeBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return b.client.ethClient().SuggestGasPrice(ctx)
+}
+
+func (b *nodeBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return b.client.ethClient().SuggestGasTipCap(ctx)
+}
+
+func (b *nodeBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return b.client.ethClient().EstimateGas(ctx, call)
+}
+
+func (b *nodeBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return b.client.ethClient().SendTransaction(ctx, tx)
+}
+
+func (b *nodeBackend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return b.client.ethClient().FilterLogs(ctx, query)
+}
+
+// SubscribeFilterLogs subscribes on the active endpoint until the client
+// switches to another one
+func (b *nodeBackend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	conn := b.client.node()
+	sub, err := conn.eth.SubscribeFilterLogs(ctx, query, ch)
+	if err != nil {
+		return nil, err
+	}
+	return untilRetired(conn, sub), nil
+}
+
+// checkEndpoints probes the endpoints every check_interval and fails over
+// when the active one is no longer the best
+func (c *Client) checkEndpoints() {
+	if len(c.endpoints.endpoints) < 2 || c.config.Health.CheckInterval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(c.config.Health.CheckInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+
+		c.endpoints.probeAll(c.ctx)
+		e := c.endpoints.best()
+		if e == nil || e == c.endpoints.current() {
+			continue
+		}
+		if err := c.useEndpoint(e); err != nil {
+			logger.Warn("Fail over ethereum endpoint", "addr", e.addr, "err", err.Error())
+		}
+	}
+}
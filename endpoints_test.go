@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// connectedEndpoint returns an endpoint connected to an empty in process node
+func connectedEndpoint(t *testing.T, addr string, head uint64) *endpoint {
+	rpcCli := rpc.DialInProc(rpc.NewServer())
+	t.Cleanup(rpcCli.Close)
+	return &endpoint{
+		addr:   addr,
+		rpc:    rpcCli,
+		eth:    ethclient.NewClient(rpcCli),
+		head:   head,
+		ok:     true,
+		probes: []bool{true},
+	}
+}
+
+func TestEndpointPoolBest(t *testing.T) {
+	a := connectedEndpoint(t, "a", 100)
+	b := connectedEndpoint(t, "b", 103)
+	c := connectedEndpoint(t, "c", 102)
+	pool := &endpointPool{cfg: Health{MaxLag: 3, MaxErrorRate: 0.2}, endpoints: []*endpoint{a, b, c}}
+	pool.setCurrent(a)
+
+	if best := pool.best(); best != a {
+		t.Fatalf("best %s, want the active endpoint a while it is healthy", best.addr)
+	}
+
+	a.head = 99
+	if best := pool.best(); best != b {
+		t.Fatalf("best %s, want b with the highest head", best.addr)
+	}
+
+	b.probes = []bool{true, false, false}
+	if best := pool.best(); best != c {
+		t.Fatalf("best %s, want c as b errs too often", best.addr)
+	}
+}
+
+func TestUseEndpointRetiresConnection(t *testing.T) {
+	a := connectedEndpoint(t, "a", 100)
+	b := connectedEndpoint(t, "b", 100)
+	c := &Client{endpoints: &endpointPool{endpoints: []*endpoint{a, b}}}
+	if err := c.useEndpoint(a); err != nil {
+		t.Fatalf("use a: %v", err)
+	}
+	first := c.node()
+
+	// using the same endpoint again keeps the connection
+	if err := c.useEndpoint(a); err != nil {
+		t.Fatalf("use a again: %v", err)
+	}
+	if c.node() != first {
+		t.Fatal("connection replaced without switching the endpoint")
+	}
+
+	inner := event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+	sub := untilRetired(first, inner)
+	defer sub.Unsubscribe()
+
+	if err := c.useEndpoint(b); err != nil {
+		t.Fatalf("use b: %v", err)
+	}
+	if c.ethClient() != b.eth || c.endpoints.current() != b {
+		t.Fatal("client not moved onto b")
+	}
+	select {
+	case err := <-sub.Err():
+		if !errors.Is(err, errEndpointSwitched) {
+			t.Fatalf("subscription error %v, want %v", err, errEndpointSwitched)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription on the retired connection still alive")
+	}
+
+	// the previous endpoint stays connected for calls in flight on it
+	if rpcCli, _ := a.clients(); rpcCli == nil {
+		t.Fatal("previous endpoint closed")
+	}
+}
+
+// slowNode answers eth_blockNumber once released
+type slowNode struct {
+	release chan struct{}
+}
+
+func (n *slowNode) BlockNumber() hexutil.Uint64 {
+	<-n.release
+	return 7
+}
+
+func TestProbeDoesNotHoldLock(t *testing.T) {
+	node := &slowNode{release: make(chan struct{})}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("register fake node: %v", err)
+	}
+	rpcCli := rpc.DialInProc(server)
+	defer rpcCli.Close()
+	e := &endpoint{addr: "slow", rpc: rpcCli, eth: ethclient.NewClient(rpcCli)}
+
+	done := make(chan struct{})
+	go func() {
+		e.probe(context.Background())
+		close(done)
+	}()
+
+	healthy := make(chan endpointHealth)
+	go func() { healthy <- e.health() }()
+	select {
+	case <-healthy:
+	case <-time.After(time.Second):
+		t.Fatal("health blocked by a probe in flight")
+	}
+
+	close(node.release)
+	<-done
+	if h := e.health(); !h.ok || h.head != 7 {
+		t.Fatalf("health %+v after the probe, want ok at head 7", h)
+	}
+}
+
+// pollNode reports every eth_blockNumber
+type pollNode struct {
+	polled chan struct{}
+}
+
+func (n *pollNode) BlockNumber() hexutil.Uint64 {
+	select {
+	case n.polled <- struct{}{}:
+	default:
+	}
+	return 100
+}
+
+func TestHeadPollEndsOnSwitch(t *testing.T) {
+	node := &pollNode{polled: make(chan struct{}, 1)}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("register fake node: %v", err)
+	}
+	rpcCli := rpc.DialInProc(server)
+	defer rpcCli.Close()
+	a := &endpoint{addr: "http://a", rpc: rpcCli, eth: ethclient.NewClient(rpcCli), ok: true}
+	b := connectedEndpoint(t, "ws://b", 100)
+	c := &Client{ctx: context.Background(), config: defaultConfig(), endpoints: &endpointPool{endpoints: []*endpoint{a, b}}}
+	c.heads = newHeadTracker(c)
+	if err := c.useEndpoint(a); err != nil {
+		t.Fatalf("use a: %v", err)
+	}
+
+	polled := make(chan error, 1)
+	go func() { polled <- c.heads.poll() }()
+	<-node.polled
+
+	if err := c.useEndpoint(b); err != nil {
+		t.Fatalf("use b: %v", err)
+	}
+	select {
+	case err := <-polled:
+		if !errors.Is(err, errEndpointSwitched) {
+			t.Fatalf("poll error %v, want %v", err, errEndpointSwitched)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("head polling kept running after the failover")
+	}
+	if c.pollingMode() {
+		t.Fatal("polling mode kept on the ws endpoint")
+	}
+}
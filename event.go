@@ -24,13 +24,17 @@ func (c *Client) Convert2IBTP(ev *BrokerThrowInterchainEvent, timeoutHeight int6
 	if err != nil {
 		return nil, err
 	}
+	proof, err := c.eventProof(interchainEventID(ev.SrcFullID, ev.DstFullID, ev.Index), c.abi.Events["throwInterchainEvent"].ID, ev.Raw)
+	if err != nil {
+		return nil, err
+	}
 	return &pb.IBTP{
 		From:          ev.SrcFullID,
 		To:            ev.DstFullID,
 		Index:         ev.Index,
 		Type:          pb.IBTP_INTERCHAIN,
 		TimeoutHeight: timeoutHeight,
-		Proof:         []byte("1"),
+		Proof:         proof,
 		Payload:       pd,
 		Group:         group,
 	}, nil
@@ -65,8 +69,12 @@ func (c *Client) Convert2Receipt(ev *BrokerThrowReceiptEvent) (*pb.IBTP, error)
 	if err != nil {
 		return nil, err
 	}
+	proof, err := c.eventProof(receiptEventID(ev.SrcFullID, ev.DstFullID, ev.Index), c.abi.Events["throwReceiptEvent"].ID, ev.Raw)
+	if err != nil {
+		return nil, err
+	}
 
-	return generateReceipt(fullEv.SrcFullID, fullEv.DstFullID, fullEv.Index, fullEv.Results, fullEv.Typ, encrypt, fullEv.MultiStatus)
+	return generateReceipt(fullEv.SrcFullID, fullEv.DstFullID, fullEv.Index, fullEv.Results, fullEv.Typ, encrypt, fullEv.MultiStatus, proof)
 }
 
 func encodePayload(ev *BrokerThrowInterchainEvent, encrypt bool) ([]byte, error) {
@@ -94,7 +102,7 @@ func encodePayload(ev *BrokerThrowInterchainEvent, encrypt bool) ([]byte, error)
 
 func (c *Client) fillInterchainEvent(ev *BrokerThrowInterchainEvent) (*BrokerThrowInterchainEvent, bool, error) {
 	if ev.Func == "" {
-		fun, args, encrypt, group, err := c.session.GetOutMessage(pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
+		fun, args, encrypt, group, err := c.broker.getOutMessage(nil, pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
 		if err != nil {
 			return nil, false, err
 		}
@@ -122,7 +130,7 @@ func (c *Client) fillInterchainEvent(ev *BrokerThrowInterchainEvent) (*BrokerThr
 
 func (c *Client) fillReceiptEvent(ev *BrokerThrowReceiptEvent) (*BrokerThrowReceiptEvent, bool, error) {
 	if ev.Results == nil {
-		results, typ, encrypt, multiStatus, err := c.session.GetReceiptMessage(pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
+		results, typ, encrypt, multiStatus, err := c.broker.getReceiptMessage(nil, pb.GenServicePair(ev.SrcFullID, ev.DstFullID), ev.Index)
 		if err != nil {
 			return nil, false, err
 		}
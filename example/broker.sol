@@ -24,9 +24,11 @@ contract Broker {
     address[] public admins;
     uint64 public adminThreshold;
 
+    event throwInterchainBatchStatus(uint64 index, string srcFullID, string destAddr, bool status, string reason);
     event throwInterchainEvent(uint64 index, string dstFullID, string srcFullID, string func, bytes[] args, bytes32 hash, string[] group);
     event throwReceiptEvent(uint64 index, string dstFullID, string srcFullID, uint64 typ, bytes[][] results, bytes32 hash, bool[] multiStatus);
     event throwReceiptStatus(bool);
+    event throwReceiptBatchStatus(uint64 index, string dstFullID, string srcAddr, bool status, string reason);
 
     address dataAddr;
 
@@ -170,13 +172,35 @@ contract Broker {
         uint64[] memory txStatus,
         bytes[][] memory signatures,
         bool[] memory isEncrypt) payable external {
-        for (uint8 i = 0; i <  srcFullID.length; ++i) {
+        for (uint i = 0; i < srcFullID.length; ++i) {
             if (serviceOrdered[BrokerData(dataAddr).stringToAddress(destAddr[i])] == true) {
                 string memory dstFullID = genFullServiceID(destAddr[i]);
                 invokeIndexUpdateWithError(srcFullID[i], dstFullID, index[i], txStatus[i], isEncrypt[i], "dst service is not ordered", uint64(1));
+                emit throwInterchainBatchStatus(index[i], srcFullID[i], destAddr[i], true, "dst service is not ordered");
                 continue;
             }
-            invokeInterchain(srcFullID[i], destAddr[i], index[i], typ[i], callFunc[i], args[i], txStatus[i], signatures[i], isEncrypt[i]);
+            (bool ok, string memory reason) = tryInvokeInterchain(srcFullID[i], destAddr[i], index[i], typ[i], callFunc[i], args[i], txStatus[i], signatures[i], isEncrypt[i]);
+            emit throwInterchainBatchStatus(index[i], srcFullID[i], destAddr[i], ok, reason);
+        }
+    }
+
+    // a reverted ibtp only rolls back its own state changes, the rest of the batch goes on
+    function tryInvokeInterchain(
+        string memory srcFullID,
+        string memory destAddr,
+        uint64 index,
+        uint64 typ,
+        string memory callFunc,
+        bytes[] memory args,
+        uint64 txStatus,
+        bytes[] memory signatures,
+        bool isEncrypt) private returns (bool, string memory) {
+        try this.invokeInterchain(srcFullID, destAddr, index, typ, callFunc, args, txStatus, signatures, isEncrypt) {
+            return (true, "");
+        } catch Error(string memory reason) {
+            return (false, reason);
+        } catch {
+            return (false, "invokeInterchain reverted");
         }
     }
 
@@ -375,6 +399,65 @@ contract Broker {
         receiptCall(outServicePair, index, isRollback, srcAddr, results);
     }
 
+    // called on src chain
+    function invokeReceipts(
+        string[] memory srcAddr,
+        string[] memory dstFullID,
+        uint64[] memory index,
+        uint64[] memory typ,
+        bytes[][][] memory results,
+        bool[][] memory multiStatus,
+        uint64[] memory txStatus,
+        bytes[][] memory signatures) payable external {
+        for (uint i = 0; i < srcAddr.length; ++i) {
+            bool ok;
+            string memory reason;
+            // the same rule the plugin uses to pick invokeMultiReceipt over invokeReceipt
+            if (multiStatus[i].length > 1 || (multiStatus[i].length == 0 && txStatus[i] != 0)) {
+                (ok, reason) = tryInvokeMultiReceipt(srcAddr[i], dstFullID[i], index[i], typ[i], results[i], multiStatus[i], txStatus[i], signatures[i]);
+            } else {
+                (ok, reason) = tryInvokeReceipt(srcAddr[i], dstFullID[i], index[i], typ[i], results[i], txStatus[i], signatures[i]);
+            }
+            emit throwReceiptBatchStatus(index[i], dstFullID[i], srcAddr[i], ok, reason);
+        }
+    }
+
+    // a failed receipt only reverts its own state changes, the rest of the batch goes on
+    function tryInvokeReceipt(
+        string memory srcAddr,
+        string memory dstFullID,
+        uint64 index,
+        uint64 typ,
+        bytes[][] memory results,
+        uint64 txStatus,
+        bytes[] memory signatures) private returns (bool, string memory) {
+        try this.invokeReceipt(srcAddr, dstFullID, index, typ, results, txStatus, signatures) {
+            return (true, "");
+        } catch Error(string memory reason) {
+            return (false, reason);
+        } catch {
+            return (false, "invokeReceipt reverted");
+        }
+    }
+
+    function tryInvokeMultiReceipt(
+        string memory srcAddr,
+        string memory dstFullID,
+        uint64 index,
+        uint64 typ,
+        bytes[][] memory results,
+        bool[] memory multiStatus,
+        uint64 txStatus,
+        bytes[] memory signatures) private returns (bool, string memory) {
+        try this.invokeMultiReceipt(srcAddr, dstFullID, index, typ, results, multiStatus, txStatus, signatures) {
+            return (true, "");
+        } catch Error(string memory reason) {
+            return (false, reason);
+        } catch {
+            return (false, "invokeMultiReceipt reverted");
+        }
+    }
+
     function receiptCall(string memory servicePair, uint64 index, bool isRollback, string memory srcAddr, bytes[][] memory results) private {
         string memory callFunc;
         bytes[] memory callArgs;
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	nodeFeeStrategy    = ""
+	legacyFeeStrategy  = "legacy"
+	dynamicFeeStrategy = "eip1559"
+	fixedFeeStrategy   = "fixed"
+)
+
+// feeStrategy fills the gas price, or the fee cap and tip of EIP-1559, of a
+// broker transaction
+type feeStrategy interface {
+	apply(ctx context.Context, c *Client, opts *bind.TransactOpts) error
+}
+
+func newFeeStrategy(cfg Fee) (feeStrategy, error) {
+	switch cfg.Strategy {
+	case nodeFeeStrategy:
+		return &nodeFee{cfg: cfg}, nil
+	case legacyFeeStrategy:
+		return &legacyFee{cfg: cfg}, nil
+	case dynamicFeeStrategy:
+		return &dynamicFee{cfg: cfg}, nil
+	case fixedFeeStrategy:
+		if cfg.GasPrice == 0 && cfg.FeeCap == 0 {
+			return nil, fmt.Errorf("fixed fee strategy requires gas_price or fee_cap")
+		}
+		return &fixedFee{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown fee strategy %s", cfg.Strategy)
+	}
+}
+
+// nodeFee leaves the fee to the suggestion of the node. With a ceiling
+// configured it fills the fees bind would use from the suggestion, limited to
+// the ceilings.
+type nodeFee struct {
+	cfg Fee
+}
+
+func (f *nodeFee) apply(ctx context.Context, c *Client, opts *bind.TransactOpts) error {
+	if f.cfg.MaxGasPrice == 0 && f.cfg.MaxFeeCap == 0 && f.cfg.MaxTipCap == 0 {
+		return nil
+	}
+
+	header, err := c.ethClient().HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("get latest header: %w", err)
+	}
+	if header.BaseFee == nil {
+		price, err := c.ethClient().SuggestGasPrice(ctx)
+		if err != nil {
+			return fmt.Errorf("suggest gas price: %w", err)
+		}
+		opts.GasPrice = capFee("gas price", price, f.cfg.MaxGasPrice)
+		return nil
+	}
+
+	tip, err := c.ethClient().SuggestGasTipCap(ctx)
+	if err != nil {
+		return fmt.Errorf("suggest gas tip cap: %w", err)
+	}
+	tip = capFee("tip cap", tip, f.cfg.MaxTipCap)
+	// bind offers twice the base fee plus the tip
+	feeCap := new(big.Int).Add(tip, new(big.Int).Mul(header.BaseFee, big.NewInt(2)))
+	feeCap = capFee("fee cap", feeCap, f.cfg.MaxFeeCap)
+	if feeCap.Cmp(tip) < 0 {
+		tip = feeCap
+	}
+
+	opts.GasFeeCap = feeCap
+	opts.GasTipCap = tip
+	return nil
+}
+
+// legacyFee scales the gas price suggested by the node
+type legacyFee struct {
+	cfg Fee
+}
+
+func (f *legacyFee) apply(ctx context.Context, c *Client, opts *bind.TransactOpts) error {
+	price, err := c.ethClient().SuggestGasPrice(ctx)
+	if err != nil {
+		return fmt.Errorf("suggest gas price: %w", err)
+	}
+
+	opts.GasPrice = capFee("gas price", mulFloat(price, f.cfg.PriceMultiplier), f.cfg.MaxGasPrice)
+	return nil
+}
+
+// dynamicFee builds EIP-1559 fees from eth_feeHistory, the fee cap covers the
+// next base fee multiplied by base_fee_multiplier plus the tip.
+type dynamicFee struct {
+	cfg Fee
+}
+
+func (f *dynamicFee) apply(ctx context.Context, c *Client, opts *bind.TransactOpts) error {
+	baseFee, tip, err := c.feeHistory(ctx, f.cfg.FeeHistoryBlocks, f.cfg.RewardPercentile)
+	if err != nil {
+		logger.Warn("get fee history, fall back to latest header", "err", err.Error())
+		header, err := c.ethClient().HeaderByNumber(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("get latest header: %w", err)
+		}
+		if header.BaseFee == nil {
+			return fmt.Errorf("the chain does not support EIP-1559")
+		}
+		baseFee = header.BaseFee
+		tip, err = c.ethClient().SuggestGasTipCap(ctx)
+		if err != nil {
+			return fmt.Errorf("suggest gas tip cap: %w", err)
+		}
+	}
+	if f.cfg.TipCap != 0 {
+		tip = new(big.Int).SetUint64(f.cfg.TipCap)
+	}
+	tip = capFee("tip cap", tip, f.cfg.MaxTipCap)
+
+	feeCap := new(big.Int).Add(mulFloat(baseFee, f.cfg.BaseFeeMultiplier), tip)
+	feeCap = capFee("fee cap", feeCap, f.cfg.MaxFeeCap)
+	if feeCap.Cmp(baseFee) < 0 {
+		logger.Warn("Fee cap is below base fee, tx may not be mined until base fee drops", "fee cap", feeCap, "base fee", baseFee)
+	}
+	if feeCap.Cmp(tip) < 0 {
+		tip = feeCap
+	}
+
+	opts.GasFeeCap = feeCap
+	opts.GasTipCap = tip
+	return nil
+}
+
+// fixedFee uses constant fees, which suits private chains
+type fixedFee struct {
+	cfg Fee
+}
+
+func (f *fixedFee) apply(_ context.Context, _ *Client, opts *bind.TransactOpts) error {
+	if f.cfg.FeeCap != 0 {
+		opts.GasFeeCap = new(big.Int).SetUint64(f.cfg.FeeCap)
+		opts.GasTipCap = new(big.Int).SetUint64(f.cfg.TipCap)
+		return nil
+	}
+
+	opts.GasPrice = new(big.Int).SetUint64(f.cfg.GasPrice)
+	return nil
+}
+
+type feeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// feeHistory returns the base fee of the next block and the average tip at
+// given percentile of the recent blocks
+func (c *Client) feeHistory(ctx context.Context, blocks uint64, percentile float64) (*big.Int, *big.Int, error) {
+	var res feeHistoryResult
+	if err := c.rpcClient().CallContext(ctx, &res, "eth_feeHistory", hexutil.Uint64(blocks), "latest", []float64{percentile}); err != nil {
+		return nil, nil, err
+	}
+	if len(res.BaseFee) == 0 {
+		return nil, nil, fmt.Errorf("empty fee history")
+	}
+
+	tip := new(big.Int)
+	var count int64
+	for _, reward := range res.Reward {
+		if len(reward) == 0 || reward[0] == nil {
+			continue
+		}
+		tip.Add(tip, reward[0].ToInt())
+		count++
+	}
+	if count != 0 {
+		tip.Div(tip, big.NewInt(count))
+	}
+
+	return res.BaseFee[len(res.BaseFee)-1].ToInt(), tip, nil
+}
+
+func mulFloat(x *big.Int, multiplier float64) *big.Int {
+	if multiplier <= 0 {
+		return new(big.Int).Set(x)
+	}
+	res, _ := new(big.Float).Mul(new(big.Float).SetInt(x), big.NewFloat(multiplier)).Int(nil)
+	return res
+}
+
+// capFee limits fee to ceiling, a zero ceiling means no limit. A clamped fee
+// may keep the tx from being mined in time, so it is logged as an error.
+func capFee(name string, fee *big.Int, ceiling uint64) *big.Int {
+	if ceiling == 0 {
+		return fee
+	}
+	max := new(big.Int).SetUint64(ceiling)
+	if fee.Cmp(max) > 0 {
+		logger.Error("Fee exceeds the ceiling, send with the ceiling", "name", name, "fee", fee, "ceiling", max)
+		return max
+	}
+	return fee
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// feeNode is a node suggesting fixed fees, with EIP-1559 enabled if baseFee
+// is not nil
+type feeNode struct {
+	baseFee  *big.Int
+	gasPrice int64
+	tip      int64
+}
+
+func (n *feeNode) GetBlockByNumber(string, bool) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100), Difficulty: new(big.Int), Extra: []byte{}, BaseFee: n.baseFee}, nil
+}
+
+func (n *feeNode) GasPrice() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(n.gasPrice))
+}
+
+func (n *feeNode) MaxPriorityFeePerGas() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(n.tip))
+}
+
+func TestNodeFeeCapsSuggestion(t *testing.T) {
+	for _, test := range []struct {
+		name                        string
+		node                        *feeNode
+		cfg                         Fee
+		wantPrice, wantCap, wantTip *big.Int
+	}{
+		{
+			name: "no ceiling",
+			node: &feeNode{gasPrice: 100},
+		},
+		{
+			name:      "legacy chain",
+			node:      &feeNode{gasPrice: 100},
+			cfg:       Fee{MaxGasPrice: 50},
+			wantPrice: big.NewInt(50),
+		},
+		{
+			name:      "legacy chain below ceiling",
+			node:      &feeNode{gasPrice: 40},
+			cfg:       Fee{MaxGasPrice: 50},
+			wantPrice: big.NewInt(40),
+		},
+		{
+			name:    "fee cap clamped",
+			node:    &feeNode{baseFee: big.NewInt(30), tip: 2},
+			cfg:     Fee{MaxFeeCap: 40},
+			wantCap: big.NewInt(40),
+			wantTip: big.NewInt(2),
+		},
+		{
+			name:    "tip clamped",
+			node:    &feeNode{baseFee: big.NewInt(30), tip: 20},
+			cfg:     Fee{MaxTipCap: 5},
+			wantCap: big.NewInt(65),
+			wantTip: big.NewInt(5),
+		},
+		{
+			name:    "tip above fee cap",
+			node:    &feeNode{baseFee: big.NewInt(30), tip: 20},
+			cfg:     Fee{MaxFeeCap: 10},
+			wantCap: big.NewInt(10),
+			wantTip: big.NewInt(10),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := rpc.NewServer()
+			if err := server.RegisterName("eth", test.node); err != nil {
+				t.Fatalf("register fake node: %v", err)
+			}
+			rpcCli := rpc.DialInProc(server)
+			defer rpcCli.Close()
+			c := &Client{}
+			c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+
+			strategy, err := newFeeStrategy(test.cfg)
+			if err != nil {
+				t.Fatalf("new fee strategy: %v", err)
+			}
+			var opts bind.TransactOpts
+			if err := strategy.apply(context.Background(), c, &opts); err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+
+			for _, fee := range []struct {
+				name      string
+				got, want *big.Int
+			}{
+				{"gas price", opts.GasPrice, test.wantPrice},
+				{"fee cap", opts.GasFeeCap, test.wantCap},
+				{"tip cap", opts.GasTipCap, test.wantTip},
+			} {
+				if (fee.got == nil) != (fee.want == nil) || fee.got != nil && fee.got.Cmp(fee.want) != 0 {
+					t.Fatalf("%s %v, want %v", fee.name, fee.got, fee.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// gasPolicy picks the gas limit of broker transactions from eth_estimateGas,
+// the configured multiplier, minimums and ceiling, or from the fallback
+// table when the estimation fails for another reason than a revert
+type gasPolicy struct {
+	cfg Gas
+	abi abi.ABI
+}
+
+func newGasPolicy(cfg Gas, abiJSON string) (*gasPolicy, error) {
+	ab, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, fmt.Errorf("abi unmarshal: %w", err)
+	}
+	return &gasPolicy{cfg: cfg, abi: ab}, nil
+}
+
+// method returns the lower case name of the broker method called by data
+func (p *gasPolicy) method(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+	m, err := p.abi.MethodById(data[:4])
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(m.RawName)
+}
+
+// scale adds the safety margin to an estimated gas
+func (p *gasPolicy) scale(estimated uint64) uint64 {
+	if p.cfg.Multiplier <= 0 {
+		return estimated
+	}
+	scaled := float64(estimated) * p.cfg.Multiplier
+	if scaled >= math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return uint64(scaled)
+}
+
+// bound applies the minimum of method and the ceiling to limit
+func (p *gasPolicy) bound(method string, limit uint64) uint64 {
+	if min := p.cfg.Min[method]; limit < min {
+		limit = min
+	}
+	if p.cfg.Ceiling != 0 && limit > p.cfg.Ceiling {
+		logger.Warn("Gas limit exceeds the ceiling", "method", method, "limit", limit, "ceiling", p.cfg.Ceiling)
+		limit = p.cfg.Ceiling
+	}
+	return limit
+}
+
+// gasLimit estimates the gas limit of tx sent from from
+func (c *Client) gasLimit(from common.Address, tx *types.Transaction) (uint64, error) {
+	method := c.gas.method(tx.Data())
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+
+	estimated, err := c.ethClient().EstimateGas(c.ctx, msg)
+	if err != nil {
+		// the tx would revert with any gas limit, so it is not sent
+		if isCallReverted(err) {
+			return 0, c.withRevertReason(fmt.Errorf("estimate gas of %s: %w", method, err))
+		}
+		fallback, ok := c.gas.cfg.Fallback[method]
+		if !ok {
+			return 0, fmt.Errorf("estimate gas of %s: %w", method, err)
+		}
+		limit := c.gas.bound(method, fallback)
+		logger.Warn("Estimate gas failed, use the fallback", "method", method, "limit", limit, "error", err.Error())
+		return limit, nil
+	}
+
+	limit := c.gas.bound(method, c.gas.scale(estimated))
+	logger.Info("Gas limit", "method", method, "estimated", estimated, "limit", limit)
+	return limit, nil
+}
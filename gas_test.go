@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// revertDataError is the JSON-RPC error of a reverted call carrying revert data
+type revertDataError struct {
+	data []byte
+}
+
+func (e *revertDataError) Error() string          { return "execution reverted" }
+func (e *revertDataError) ErrorCode() int         { return 3 }
+func (e *revertDataError) ErrorData() interface{} { return hexutil.Encode(e.data) }
+
+// estimateNode fails eth_estimateGas with err
+type estimateNode struct {
+	err error
+}
+
+func (n *estimateNode) EstimateGas(map[string]interface{}) (hexutil.Uint64, error) {
+	return 0, n.err
+}
+
+func errorData(t *testing.T, reason string) []byte {
+	t.Helper()
+	typ, _ := abi.NewType("string", "", nil)
+	data, err := abi.Arguments{{Type: typ}}.Pack(reason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append([]byte(errorSelector), data...)
+}
+
+func TestGasLimitFallback(t *testing.T) {
+	policy, err := newGasPolicy(Gas{Fallback: map[string]uint64{"invokeinterchain": 300000}}, BrokerABI)
+	if err != nil {
+		t.Fatalf("new gas policy: %v", err)
+	}
+	data, err := policy.abi.Pack("invokeInterchain", "1356:chain0:0xa", "0xb", uint64(1), uint64(0), "interchainCharge",
+		[][]byte{}, uint64(0), [][]byte{}, false)
+	if err != nil {
+		t.Fatalf("pack call: %v", err)
+	}
+	tx := types.NewTransaction(0, common.HexToAddress("0x01"), new(big.Int), 0, big.NewInt(1), data)
+
+	for _, test := range []struct {
+		name      string
+		err       error
+		wantLimit uint64
+		wantErr   string
+	}{
+		{name: "node failure", err: errors.New("node busy"), wantLimit: 300000},
+		{name: "revert", err: &revertDataError{data: errorData(t, "dest address is not in local white list")}, wantErr: "dest address is not in local white list"},
+		{name: "revert without data", err: errors.New("execution reverted"), wantErr: "execution reverted"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := rpc.NewServer()
+			if err := server.RegisterName("eth", &estimateNode{err: test.err}); err != nil {
+				t.Fatalf("register fake node: %v", err)
+			}
+			rpcCli := rpc.DialInProc(server)
+			defer rpcCli.Close()
+			c := &Client{ctx: context.Background(), gas: policy}
+			c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+
+			limit, err := c.gasLimit(common.HexToAddress("0x02"), tx)
+			if test.wantErr == "" {
+				if err != nil || limit != test.wantLimit {
+					t.Fatalf("limit %d, err %v, want the fallback %d", limit, err, test.wantLimit)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("limit %d, err %v, want an error with %q", limit, err, test.wantErr)
+			}
+			if category := classifyError(err); category != revertError {
+				t.Fatalf("error category %s, want %s", category, revertError)
+			}
+		})
+	}
+}
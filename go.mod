@@ -15,6 +15,7 @@ require (
 	github.com/meshplus/bitxhub-model v1.28.0
 	github.com/meshplus/pier v1.24.1-0.20230119083935-a568b0398d3c
 	github.com/spf13/viper v1.8.1
+	github.com/syndtr/goleveldb v1.0.1-0.20210819022825-2ae1ddf74ef7
 	github.com/urfave/cli v1.22.1
 )
 
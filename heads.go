@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// headStaleAfter is how long a cached head is trusted before it is
+	// fetched again on demand
+	headStaleAfter = time.Minute
+
+	// headResubscribeWait is the pause before a dropped head subscription
+	// is set up again
+	headResubscribeWait = 5 * time.Second
+)
+
+// headTracker follows the chain head once for the whole client, by a new
+// head subscription or by polling like the event source. Pending txs register
+// with it and are woken on every new head instead of polling the node alone.
+type headTracker struct {
+	client  *Client
+	lock    sync.Mutex
+	head    uint64
+	updated time.Time
+	subs    map[chan uint64]struct{}
+
+	// the tagged block is queried at most once per head
+	taggedAt     uint64
+	taggedHeight uint64
+}
+
+func newHeadTracker(c *Client) *headTracker {
+	return &headTracker{
+		client: c,
+		subs:   make(map[chan uint64]struct{}),
+	}
+}
+
+func (t *headTracker) start() {
+	go t.run()
+}
+
+// current returns the latest head, fetching it from the node when the
+// tracker has no recent one
+func (t *headTracker) current() (uint64, error) {
+	t.lock.Lock()
+	head, updated := t.head, t.updated
+	t.lock.Unlock()
+
+	if !updated.IsZero() && time.Since(updated) < headStaleAfter {
+		return head, nil
+	}
+	return t.refresh()
+}
+
+// tagged returns the height of the block tagged with tag as of the current
+// head
+func (t *headTracker) tagged(tag string) (uint64, error) {
+	head, err := t.current()
+	if err != nil {
+		return 0, err
+	}
+
+	t.lock.Lock()
+	if t.taggedAt == head && t.taggedHeight != 0 {
+		tagged := t.taggedHeight
+		t.lock.Unlock()
+		return tagged, nil
+	}
+	t.lock.Unlock()
+
+	tagged, err := t.client.taggedHeight(tag)
+	if err != nil {
+		return 0, err
+	}
+	t.lock.Lock()
+	t.taggedAt, t.taggedHeight = head, tagged
+	t.lock.Unlock()
+	return tagged, nil
+}
+
+// subscribe registers for new heads. Only the latest head is kept for a slow
+// receiver, and the returned func must be called once it is done.
+func (t *headTracker) subscribe() (<-chan uint64, func()) {
+	ch := make(chan uint64, 1)
+	t.lock.Lock()
+	t.subs[ch] = struct{}{}
+	t.lock.Unlock()
+
+	return ch, func() {
+		t.lock.Lock()
+		delete(t.subs, ch)
+		t.lock.Unlock()
+	}
+}
+
+func (t *headTracker) refresh() (uint64, error) {
+	head, err := t.client.ethClient().BlockNumber(t.client.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get best block: %w", err)
+	}
+	t.update(head)
+	return head, nil
+}
+
+func (t *headTracker) update(head uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	changed := head != t.head
+	t.head = head
+	t.updated = time.Now()
+	if !changed {
+		return
+	}
+	for ch := range t.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- head
+	}
+}
+
+func (t *headTracker) run() {
+	for {
+		// the head source follows the endpoint in use, a failover may move
+		// it between a subscription and polling
+		var err error
+		if t.client.pollingMode() {
+			err = t.poll()
+		} else {
+			err = t.follow()
+		}
+		if t.client.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errEndpointSwitched) {
+			logger.Info("Follow chain head on the new endpoint", "polling", t.client.pollingMode())
+			continue
+		}
+		logger.Warn("Head subscription dropped", "error", err)
+		if _, err := t.refresh(); err != nil {
+			logger.Warn("Refresh head failed", "error", err.Error())
+		}
+
+		select {
+		case <-time.After(headResubscribeWait):
+		case <-t.client.ctx.Done():
+			return
+		}
+	}
+}
+
+// follow delivers the heads of a new head subscription until it fails
+func (t *headTracker) follow() error {
+	headers := make(chan *types.Header, 16)
+	conn := t.client.node()
+	sub, err := conn.eth.SubscribeNewHead(t.client.ctx, headers)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case header := <-headers:
+			t.update(header.Number.Uint64())
+		case err := <-sub.Err():
+			if err == nil {
+				err = fmt.Errorf("subscription closed")
+			}
+			return err
+		case <-conn.retired:
+			return errEndpointSwitched
+		case <-t.client.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// poll refreshes the head every poll_interval until the endpoint is switched
+func (t *headTracker) poll() error {
+	conn := t.client.node()
+	ticker := time.NewTicker(time.Duration(t.client.config.Ether.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if _, err := t.refresh(); err != nil {
+			logger.Warn("Refresh head failed", "error", err.Error())
+		}
+
+		select {
+		case <-ticker.C:
+		case <-conn.retired:
+			return errEndpointSwitched
+		case <-t.client.ctx.Done():
+			return nil
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -8,6 +9,11 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/fatih/color"
 	"github.com/gobuffalo/packd"
 	"github.com/gobuffalo/packr/v2"
@@ -67,6 +73,267 @@ var startCMD = cli.Command{
 	},
 }
 
+var configFlag = cli.StringFlag{
+	Name:     "config",
+	Usage:    "Specify the plugin configuration directory",
+	Required: true,
+}
+
+var checkpointCMD = cli.Command{
+	Name:  "checkpoint",
+	Usage: "Inspect or reset the checkpoint of processed broker events",
+	Subcommands: []cli.Command{
+		{
+			Name:  "show",
+			Usage: "Show the last processed block height and log index",
+			Flags: []cli.Flag{configFlag},
+			Action: func(ctx *cli.Context) error {
+				return withCheckpointStore(ctx, func(store *CheckpointStore) error {
+					cp := store.Last()
+					if cp == nil {
+						fmt.Println("No checkpoint recorded")
+						return nil
+					}
+					fmt.Printf("Height: %d\nLog index: %d\n", cp.Height, cp.LogIndex)
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "reset",
+			Usage: "Remove the checkpoint, the plugin then resumes from start_height",
+			Flags: []cli.Flag{configFlag},
+			Action: func(ctx *cli.Context) error {
+				return withCheckpointStore(ctx, func(store *CheckpointStore) error {
+					if err := store.Reset(); err != nil {
+						return err
+					}
+					color.Green("Reset checkpoint successfully")
+					return nil
+				})
+			},
+		},
+	},
+}
+
+func withCheckpointStore(ctx *cli.Context, fn func(store *CheckpointStore) error) error {
+	configPath := ctx.String("config")
+	cfg, err := UnmarshalConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("unmarshal config for plugin :%w", err)
+	}
+
+	store, err := NewCheckpointStore(filepath.Join(configPath, cfg.Ether.CheckpointPath), cfg.Ether.ContractAddress)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return fn(store)
+}
+
+// cliTxTimeout bounds how long a command waits for its tx to be mined
+const cliTxTimeout = 5 * time.Minute
+
+var (
+	keyFlag = cli.StringFlag{
+		Name:  "key",
+		Usage: "Specify the keystore file of an admin, the plugin key by default",
+	}
+	passwordFlag = cli.StringFlag{
+		Name:  "password",
+		Usage: "Specify the file keeping the password of the admin keystore",
+	}
+)
+
+// brokerCLI is the relay broker bound on the first reachable node of the
+// plugin configuration
+type brokerCLI struct {
+	cfg        *Config
+	configPath string
+	address    common.Address
+	ethClient  *ethclient.Client
+	broker     *Broker
+	relay      *relayBroker
+}
+
+func withBroker(ctx *cli.Context, fn func(b *brokerCLI) error) error {
+	configPath := ctx.String("config")
+	cfg, err := UnmarshalConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("unmarshal config for plugin :%w", err)
+	}
+
+	dialErr := errNoEndpoint
+	var rpcCli *rpc.Client
+	for _, addr := range cfg.Ether.Addr {
+		if rpcCli, err = rpc.Dial(addr); err == nil {
+			break
+		}
+		dialErr = fmt.Errorf("dial ethereum node %s: %w", addr, err)
+	}
+	if rpcCli == nil {
+		return dialErr
+	}
+	etherCli := ethclient.NewClient(rpcCli)
+	defer etherCli.Close()
+
+	address := common.HexToAddress(cfg.Ether.ContractAddress)
+	broker, err := NewBroker(address, etherCli)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate a Broker contract: %w", err)
+	}
+
+	return fn(&brokerCLI{
+		cfg:        cfg,
+		configPath: configPath,
+		address:    address,
+		ethClient:  etherCli,
+		broker:     broker,
+		relay:      &relayBroker{session: &BrokerSession{Contract: broker}},
+	})
+}
+
+// transactor unlocks the admin key given by the key and password flags, or
+// the key of the plugin if they are not set
+func (b *brokerCLI) transactor(ctx *cli.Context) (*bind.TransactOpts, error) {
+	keyPath := filepath.Join(b.configPath, b.cfg.Ether.KeyPath)
+	passwordPath := filepath.Join(b.configPath, b.cfg.Ether.Password)
+	if ctx.String(keyFlag.Name) != "" {
+		keyPath, passwordPath = ctx.String(keyFlag.Name), ctx.String(passwordFlag.Name)
+	}
+	key, err := loadKey(keyPath, passwordPath)
+	if err != nil {
+		return nil, err
+	}
+
+	chainID, err := b.ethClient.ChainID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("cannot get ethereum chain ID: %w", err)
+	}
+	return bind.NewKeyedTransactorWithChainID(key.PrivateKey, chainID)
+}
+
+// wait waits until tx is mined and fails if it reverted
+func (b *brokerCLI) wait(tx *types.Transaction) (*types.Receipt, error) {
+	fmt.Printf("Sent tx %s, waiting for it to be mined\n", tx.Hash().Hex())
+	ctx, cancel := context.WithTimeout(context.Background(), cliTxTimeout)
+	defer cancel()
+
+	receipt, err := bind.WaitMined(ctx, b.ethClient, tx)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return receipt, fmt.Errorf("tx %s reverted in block %d", tx.Hash().Hex(), receipt.BlockNumber)
+	}
+	return receipt, nil
+}
+
+// validators reads the validator set of the broker
+func (b *brokerCLI) validators() ([]common.Address, uint64, error) {
+	return b.relay.getValidators(nil)
+}
+
+func printValidators(title string, validators []common.Address, threshold uint64) {
+	fmt.Printf("%s (threshold %d):\n", title, threshold)
+	for _, addr := range validators {
+		fmt.Printf("  %s\n", addr.Hex())
+	}
+}
+
+var validatorsCMD = cli.Command{
+	Name:  "validators",
+	Usage: "Compare or synchronize the validators of the relay broker with pier",
+	Subcommands: []cli.Command{
+		{
+			Name:  "show",
+			Usage: "Show the validators of the broker and how they differ from pier",
+			Flags: []cli.Flag{configFlag},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					validators, threshold, err := b.validators()
+					if err != nil {
+						return err
+					}
+					printValidators("Broker validators", validators, threshold)
+
+					expected, err := loadExpectedValidators(b.configPath, b.cfg.Validators)
+					if err != nil {
+						return err
+					}
+					printValidators(fmt.Sprintf("Pier validators from %s", expected.source), expected.validators, expected.threshold)
+					fmt.Printf("Drift: %s\n", diffValidators(validators, threshold, expected))
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "sync",
+			Usage: "Propose the validators of pier to the broker through setValidators",
+			Flags: []cli.Flag{
+				configFlag,
+				keyFlag,
+				passwordFlag,
+				cli.Uint64Flag{
+					Name:  "threshold",
+					Usage: "Specify the threshold, the configured or current one by default",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withBroker(ctx, func(b *brokerCLI) error {
+					current, currentThreshold, err := b.validators()
+					if err != nil {
+						return err
+					}
+					expected, err := loadExpectedValidators(b.configPath, b.cfg.Validators)
+					if err != nil {
+						return err
+					}
+					threshold := ctx.Uint64("threshold")
+					if threshold == 0 {
+						threshold = expected.threshold
+					}
+					if threshold == 0 {
+						threshold = currentThreshold
+					}
+					if threshold == 0 || threshold > uint64(len(expected.validators)) {
+						return fmt.Errorf("threshold %d is invalid for %d validators", threshold, len(expected.validators))
+					}
+
+					expected.threshold = threshold
+					drift := diffValidators(current, currentThreshold, expected)
+					if drift.empty() {
+						color.Green("Validators are in sync")
+						return nil
+					}
+					fmt.Printf("Drift: %s\n", drift)
+
+					opts, err := b.transactor(ctx)
+					if err != nil {
+						return err
+					}
+					tx, err := b.broker.SetValidators(opts, expected.validators, threshold)
+					if err != nil {
+						return err
+					}
+					if _, err := b.wait(tx); err != nil {
+						return err
+					}
+
+					validators, threshold, err := b.validators()
+					if err != nil {
+						return err
+					}
+					printValidators("Broker validators", validators, threshold)
+					color.Green("Synchronized validators successfully")
+					return nil
+				})
+			},
+		},
+	},
+}
+
 var (
 	// CurrentCommit current git commit hash
 	CurrentCommit = ""
@@ -104,6 +371,9 @@ func main() {
 		initCMD,
 		startCMD,
 		versionCMD,
+		checkpointCMD,
+		validatorsCMD,
+		adminCMD,
 	}
 
 	err := app.Run(os.Args)
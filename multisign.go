@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meshplus/bitxhub-model/pb"
+	"github.com/meshplus/pier-client-ethereum/multisign"
+)
+
+// validatorsTTL is how long the validator set read from the broker is used
+// before it is read again
+const validatorsTTL = time.Minute
+
+// validatorCache keeps the validator set of the broker and the chain ids the
+// full service ids of this appchain are built from
+type validatorCache struct {
+	lock       sync.Mutex
+	validators []common.Address
+	threshold  uint64
+	fetched    time.Time
+	bxhID      string
+	appchainID string
+}
+
+// loadValidators returns the validators and threshold of the broker, read again
+// if refresh is set or the cached ones are older than validatorsTTL
+func (c *Client) loadValidators(refresh bool) ([]common.Address, uint64, error) {
+	set, ok := c.broker.(validatorSet)
+	if !ok {
+		return nil, 0, unsupported("validator set", c.broker)
+	}
+
+	cache := &c.validators
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if !refresh && !cache.fetched.IsZero() && time.Since(cache.fetched) < validatorsTTL {
+		return cache.validators, cache.threshold, nil
+	}
+
+	validators, threshold, err := set.getValidators(&bind.CallOpts{Context: c.ctx})
+	if err != nil {
+		return nil, 0, fmt.Errorf("get validators: %w", err)
+	}
+	cache.validators, cache.threshold, cache.fetched = validators, threshold, time.Now()
+	return validators, threshold, nil
+}
+
+// fullServiceID builds the full id of a local service like the broker does
+func (c *Client) fullServiceID(serviceID string) (string, error) {
+	cache := &c.validators
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if cache.bxhID == "" {
+		bxhID, appchainID, err := c.broker.getChainID(nil)
+		if err != nil {
+			return "", err
+		}
+		cache.bxhID, cache.appchainID = bxhID, appchainID
+	}
+	return fmt.Sprintf("%s:%s:%s", cache.bxhID, cache.appchainID, serviceID), nil
+}
+
+// checkMultiSign verifies a multi-signature before the broker does it on
+// chain. The validator set is read again before rejecting, in case it has
+// just been updated. Brokers without validators are not checked, and if the
+// set can't be read the check is left to the broker.
+func (c *Client) checkMultiSign(hash common.Hash, signatures [][]byte) error {
+	validators, threshold, err := c.loadValidators(false)
+	if err != nil {
+		logger.Warn("Skip local multi-signature check", "error", err.Error())
+		return nil
+	}
+
+	err = multisign.Check(hash, signatures, validators, threshold)
+	if !errors.Is(err, multisign.ErrInsufficientSignatures) {
+		return err
+	}
+	if validators, threshold, err = c.loadValidators(true); err != nil {
+		logger.Warn("Skip local multi-signature check", "error", err.Error())
+		return nil
+	}
+	return multisign.Check(hash, signatures, validators, threshold)
+}
+
+func (c *Client) checkInterchainSign(from string, index uint64, serviceID string, typ uint64, callFunc string, args [][]byte, txStatus uint64, signatures [][]byte) error {
+	if _, ok := c.broker.(validatorSet); !ok {
+		return nil
+	}
+	dstFullID, err := c.fullServiceID(serviceID)
+	if err != nil {
+		logger.Warn("Skip local multi-signature check", "error", err.Error())
+		return nil
+	}
+	return c.checkMultiSign(multisign.InterchainHash(from, dstFullID, index, typ, callFunc, args, txStatus), signatures)
+}
+
+func (c *Client) checkMultiInterchainSign(from string, index uint64, serviceID string, typ uint64, callFunc string, args [][][]byte, txStatus uint64, signatures [][]byte) error {
+	if _, ok := c.broker.(validatorSet); !ok {
+		return nil
+	}
+	dstFullID, err := c.fullServiceID(serviceID)
+	if err != nil {
+		logger.Warn("Skip local multi-signature check", "error", err.Error())
+		return nil
+	}
+	return c.checkMultiSign(multisign.MultiInterchainHash(from, dstFullID, index, typ, callFunc, args, txStatus), signatures)
+}
+
+func (c *Client) checkReceiptSign(serviceID string, to string, index uint64, typ uint64, results [][][]byte, txStatus uint64, signatures [][]byte) error {
+	if _, ok := c.broker.(validatorSet); !ok {
+		return nil
+	}
+	srcFullID, err := c.fullServiceID(serviceID)
+	if err != nil {
+		logger.Warn("Skip local multi-signature check", "error", err.Error())
+		return nil
+	}
+
+	var out *multisign.CallFunc
+	if typ == 0 {
+		fun, args, _, _, err := c.broker.getOutMessage(nil, pb.GenServicePair(srcFullID, to), index)
+		if err != nil {
+			logger.Warn("Skip local multi-signature check", "error", err.Error())
+			return nil
+		}
+		out = &multisign.CallFunc{Func: fun, Args: args}
+	}
+	hash, err := multisign.ReceiptHash(srcFullID, to, index, typ, results, out, txStatus)
+	if err != nil {
+		return err
+	}
+	return c.checkMultiSign(hash, signatures)
+}
@@ -0,0 +1,148 @@
+// Package multisign checks the BitXHub multi-signatures of IBTPs the way the
+// relay broker does on chain, see checkInterchainMultiSigns,
+// checkMultiInterchainMultiSigns and checkReceiptMultiSigns in broker_data.sol.
+//
+// The signed hash is keccak256 of
+//
+//	abi.encodePacked(srcFullID, dstFullID, index, typ, keccak256(data), txStatus)
+//
+// where the integers are packed as 8 byte big endian uint64 and data is
+//
+//	interchain        abi.encodePacked(callFunc, uint64(0), args...)
+//	multi interchain  abi.encodePacked(callFunc, uint64(1), uint64(len(args[0])), args[0]..., args[1]..., ...)
+//	receipt           the results flattened in order, or for typ 0 the func and
+//	                  args of the interchain call the receipt answers
+//
+// A signature is the 65 byte [R || S || V] with V being 0 or 1, and the hash
+// is signed without any message prefix.
+package multisign
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SignatureLength is the length of a multi-signature entry
+const SignatureLength = 65
+
+// ErrInsufficientSignatures is returned when fewer validators than the
+// threshold signed the hash
+var ErrInsufficientSignatures = errors.New("insufficient validator signatures")
+
+// CallFunc is an interchain call as kept in the outMessages of the broker
+type CallFunc struct {
+	Func string
+	Args [][]byte
+}
+
+func uint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func ibtpHash(srcFullID, dstFullID string, index, typ uint64, data []byte, txStatus uint64) common.Hash {
+	var packed []byte
+	packed = append(packed, srcFullID...)
+	packed = append(packed, dstFullID...)
+	packed = append(packed, uint64Bytes(index)...)
+	packed = append(packed, uint64Bytes(typ)...)
+	packed = append(packed, crypto.Keccak256(data)...)
+	packed = append(packed, uint64Bytes(txStatus)...)
+	return crypto.Keccak256Hash(packed)
+}
+
+// InterchainHash is the hash checkInterchainMultiSigns verifies
+func InterchainHash(srcFullID, dstFullID string, index, typ uint64, callFunc string, args [][]byte, txStatus uint64) common.Hash {
+	var data []byte
+	data = append(data, callFunc...)
+	data = append(data, uint64Bytes(0)...)
+	for _, arg := range args {
+		data = append(data, arg...)
+	}
+	return ibtpHash(srcFullID, dstFullID, index, typ, data, txStatus)
+}
+
+// MultiInterchainHash is the hash checkMultiInterchainMultiSigns verifies
+func MultiInterchainHash(srcFullID, dstFullID string, index, typ uint64, callFunc string, args [][][]byte, txStatus uint64) common.Hash {
+	var data []byte
+	data = append(data, callFunc...)
+	data = append(data, uint64Bytes(1)...)
+	if len(args) == 0 {
+		data = append(data, uint64Bytes(0)...)
+	} else {
+		data = append(data, uint64Bytes(uint64(len(args[0])))...)
+	}
+	for _, arg := range args {
+		for _, a := range arg {
+			data = append(data, a...)
+		}
+	}
+	return ibtpHash(srcFullID, dstFullID, index, typ, data, txStatus)
+}
+
+// ReceiptHash is the hash checkReceiptMultiSigns verifies. out is the
+// interchain call the receipt answers, it is only used and required for typ 0.
+func ReceiptHash(srcFullID, dstFullID string, index, typ uint64, results [][][]byte, out *CallFunc, txStatus uint64) (common.Hash, error) {
+	var data []byte
+	if typ == 0 {
+		if out == nil {
+			return common.Hash{}, fmt.Errorf("receipt of type 0 needs the interchain call it answers")
+		}
+		data = append(data, out.Func...)
+		for _, arg := range out.Args {
+			data = append(data, arg...)
+		}
+	} else {
+		for _, result := range results {
+			for _, r := range result {
+				data = append(data, r...)
+			}
+		}
+	}
+	return ibtpHash(srcFullID, dstFullID, index, typ, data, txStatus), nil
+}
+
+// Signers returns the distinct validators which signed hash, in the order of
+// their first signature. Malformed signatures and non validators are skipped.
+func Signers(hash common.Hash, signatures [][]byte, validators []common.Address) []common.Address {
+	isValidator := make(map[common.Address]bool, len(validators))
+	for _, v := range validators {
+		isValidator[v] = true
+	}
+
+	var signers []common.Address
+	seen := make(map[common.Address]bool)
+	for _, sig := range signatures {
+		// the broker recovers with v + 27, only 27 and 28 are valid
+		if len(sig) != SignatureLength || sig[SignatureLength-1] > 1 {
+			continue
+		}
+		pub, err := crypto.SigToPub(hash.Bytes(), sig)
+		if err != nil {
+			continue
+		}
+		addr := crypto.PubkeyToAddress(*pub)
+		if !isValidator[addr] || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		signers = append(signers, addr)
+	}
+	return signers
+}
+
+// Check succeeds when at least threshold distinct validators signed hash.
+// As on chain, a zero threshold is never met.
+func Check(hash common.Hash, signatures [][]byte, validators []common.Address, threshold uint64) error {
+	signers := Signers(hash, signatures, validators)
+	if threshold == 0 || uint64(len(signers)) < threshold {
+		return fmt.Errorf("%w: %d of %d required from %d signatures on %s",
+			ErrInsufficientSignatures, len(signers), threshold, len(signatures), hash.Hex())
+	}
+	return nil
+}
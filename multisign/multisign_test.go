@@ -0,0 +1,138 @@
+package multisign
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// The vectors below were computed outside of this package by packing the
+// fields as broker_data.sol does and signing with a plain secp256k1 signer.
+const (
+	srcFullID = "1356:chain0:0xa"
+	dstFullID = "1356:chain1:0xb"
+
+	// keccak256(abi.encodePacked(src, dst, uint64(1), uint64(0),
+	// keccak256(abi.encodePacked("interchainCharge", uint64(0), "alice", "100")), uint64(0)))
+	interchainVector = "0x04e5f814f3fd0e80447897b105bad0f2d2410e2ab566863985be6e8780c6c4aa"
+	// signatures of interchainVector by validator1 and validator2
+	validator1Sig = "0xd84df72f8f0c598a9a2a26d446ce8955f2e00b51a9c3ac6301930796c389ee7c08150b4f3feafe51f816cc74976bc3ed07b357dc971f6719eca301aabe0bd17e00"
+	validator2Sig = "0x96ebffd7f8a496a6344222ca2b7d3b2b3e33a791ba80cee66a78f51aac1ea8e10c03fdf7b9f5f4d894238bc54ca375a834f2080a4ea029ff7b1053ac7cf3f22d00"
+)
+
+var (
+	validator1 = common.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23")
+	validator2 = common.HexToAddress("0xbfaa37cc9292fddb35f16fbf5e19949e980d8122")
+)
+
+func TestHashes(t *testing.T) {
+	receipt0, err := ReceiptHash(srcFullID, dstFullID, 1, 0, nil,
+		&CallFunc{Func: "interchainCharge", Args: [][]byte{[]byte("alice"), []byte("100")}}, 1)
+	if err != nil {
+		t.Fatalf("receipt hash of type 0: %v", err)
+	}
+	receipt1, err := ReceiptHash(srcFullID, dstFullID, 1, 1,
+		[][][]byte{{[]byte("true"), []byte("ok")}, {[]byte("done")}}, nil, 0)
+	if err != nil {
+		t.Fatalf("receipt hash of type 1: %v", err)
+	}
+
+	for _, test := range []struct {
+		name string
+		got  common.Hash
+		want string
+	}{
+		{
+			name: "interchain",
+			got:  InterchainHash(srcFullID, dstFullID, 1, 0, "interchainCharge", [][]byte{[]byte("alice"), []byte("100")}, 0),
+			want: interchainVector,
+		},
+		{
+			name: "multi interchain",
+			got: MultiInterchainHash(srcFullID, dstFullID, 2, 0, "interchainCharge",
+				[][][]byte{{[]byte("alice"), []byte("100")}, {[]byte("bob"), []byte("200")}}, 0),
+			want: "0xa09c40d9c8d0a41d175a55031fc7d32d0df858a81e49af4cea29f1dc7d2cbb14",
+		},
+		{
+			name: "receipt of the interchain call",
+			got:  receipt0,
+			want: "0xc254e3d18383ac887b5c2284c581a22167cf4fadf1c00a51076c6ec1a6adebae",
+		},
+		{
+			name: "receipt with results",
+			got:  receipt1,
+			want: "0x121671d48991b5285fc3f89740ff36c76f946cbc842809a397ad6e0c9d3144cc",
+		},
+	} {
+		if test.got != common.HexToHash(test.want) {
+			t.Errorf("%s hash %s, want %s", test.name, test.got.Hex(), test.want)
+		}
+	}
+
+	if _, err := ReceiptHash(srcFullID, dstFullID, 1, 0, nil, nil, 0); err == nil {
+		t.Error("receipt hash of type 0 without the interchain call succeeded")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	hash := common.HexToHash(interchainVector)
+	sig1 := hexutil.MustDecode(validator1Sig)
+	sig2 := hexutil.MustDecode(validator2Sig)
+	validators := []common.Address{validator1, validator2}
+
+	outsiderKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outsiderSig, err := crypto.Sign(hash.Bytes(), outsiderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same signature with V as 27, which the broker rejects
+	legacyV := append([]byte{}, sig2...)
+	legacyV[SignatureLength-1] += 27
+
+	for _, test := range []struct {
+		name        string
+		sigs        [][]byte
+		threshold   uint64
+		wantSigners []common.Address
+	}{
+		{name: "threshold met", sigs: [][]byte{sig2, sig1}, threshold: 2, wantSigners: []common.Address{validator2, validator1}},
+		{name: "duplicate signature", sigs: [][]byte{sig1, sig1}, threshold: 2, wantSigners: []common.Address{validator1}},
+		{name: "non validator", sigs: [][]byte{sig1, outsiderSig}, threshold: 2, wantSigners: []common.Address{validator1}},
+		{name: "malformed signature", sigs: [][]byte{sig1, sig2[:64]}, threshold: 2, wantSigners: []common.Address{validator1}},
+		{name: "legacy recovery id", sigs: [][]byte{sig1, legacyV}, threshold: 2, wantSigners: []common.Address{validator1}},
+		{name: "zero threshold", sigs: [][]byte{sig1, sig2}, threshold: 0, wantSigners: []common.Address{validator1, validator2}},
+		{name: "single signer", sigs: [][]byte{sig1}, threshold: 1, wantSigners: []common.Address{validator1}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			signers := Signers(hash, test.sigs, validators)
+			if len(signers) != len(test.wantSigners) {
+				t.Fatalf("signers %v, want %v", signers, test.wantSigners)
+			}
+			for i := range signers {
+				if signers[i] != test.wantSigners[i] {
+					t.Fatalf("signers %v, want %v", signers, test.wantSigners)
+				}
+			}
+
+			err := Check(hash, test.sigs, validators, test.threshold)
+			met := test.threshold != 0 && uint64(len(test.wantSigners)) >= test.threshold
+			if met && err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if !met && !errors.Is(err, ErrInsufficientSignatures) {
+				t.Fatalf("check error %v, want %v", err, ErrInsufficientSignatures)
+			}
+		})
+	}
+
+	if err := Check(InterchainHash(srcFullID, dstFullID, 2, 0, "interchainCharge", nil, 0),
+		[][]byte{sig1, sig2}, validators, 1); !errors.Is(err, ErrInsufficientSignatures) {
+		t.Fatalf("signatures of another IBTP accepted, err %v", err)
+	}
+}
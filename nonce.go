@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// nonceManager hands out the nonces of the transactor locally, so that
+// several broker transactions can be in flight at the same time.
+type nonceManager struct {
+	from     common.Address
+	next     uint64
+	free     []uint64
+	inFlight map[uint64]bool
+	synced   bool
+	lock     sync.Mutex
+}
+
+func newNonceManager(from common.Address) *nonceManager {
+	return &nonceManager{
+		from:     from,
+		inFlight: make(map[uint64]bool),
+	}
+}
+
+// acquire returns the lowest nonce not in use. The nonce is synchronized
+// from the pending state of the node whenever no transaction is in flight,
+// which picks up the transactions sent or dropped outside of the manager.
+// The nonces in flight are never handed out again by a resync.
+func (m *nonceManager) acquire(ctx context.Context, reader func(ctx context.Context, account common.Address) (uint64, error)) (uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.synced || len(m.inFlight) == 0 {
+		pending, err := reader(ctx, m.from)
+		if err != nil {
+			return 0, fmt.Errorf("get pending nonce of %s: %w", m.from.Hex(), err)
+		}
+		if pending != m.next {
+			logger.Info("Sync nonce", "account", m.from.Hex(), "local", m.next, "pending", pending)
+		}
+		m.next = pending
+		m.free = nil
+		// a nonce in flight may not have reached the node yet, it is
+		// skipped and the gaps below it are filled first
+		for nonce := range m.inFlight {
+			if nonce >= m.next {
+				m.next = nonce + 1
+			}
+		}
+		for nonce := pending; nonce < m.next; nonce++ {
+			if !m.inFlight[nonce] {
+				m.free = append(m.free, nonce)
+			}
+		}
+		m.synced = true
+	}
+
+	var nonce uint64
+	if len(m.free) != 0 {
+		nonce = m.free[0]
+		m.free = m.free[1:]
+	} else {
+		nonce = m.next
+		m.next++
+	}
+	m.inFlight[nonce] = true
+
+	return nonce, nil
+}
+
+// release gives back a nonce whose transaction was not sent or has been
+// dropped, it is reused by the next acquire to fill the gap.
+func (m *nonceManager) release(nonce uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.inFlight[nonce] {
+		return
+	}
+	delete(m.inFlight, nonce)
+	if nonce+1 == m.next {
+		m.next--
+		return
+	}
+	m.free = append(m.free, nonce)
+	sort.Slice(m.free, func(i, j int) bool { return m.free[i] < m.free[j] })
+}
+
+// confirm marks the nonce as used on chain
+func (m *nonceManager) confirm(nonce uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.inFlight, nonce)
+}
+
+// abandon stops tracking a nonce whose tx may be pending, mined or dropped,
+// the next acquire synchronizes with the node to find out
+func (m *nonceManager) abandon(nonce uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.inFlight, nonce)
+	m.synced = false
+}
+
+// reset forces the next acquire to synchronize with the node
+func (m *nonceManager) reset() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.synced = false
+}
+
+func (c *Client) transactOpts() bind.TransactOpts {
+	return c.broker.transactOpts()
+}
+
+// sendTx sends a broker transaction with a nonce from the nonce manager, the
+// fees from the fee strategy and the gas limit from the gas policy, after
+// simulating it if enabled for the mode
+func (c *Client) sendTx(send func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	opts := c.transactOpts()
+	if err := c.fee.apply(c.ctx, c, &opts); err != nil {
+		return nil, fmt.Errorf("apply fee strategy: %w", err)
+	}
+
+	built, err := buildTx(opts, send)
+	if err != nil {
+		return nil, newTxError(err)
+	}
+
+	if c.config.Simulate.enabled(c.broker.mode()) {
+		if err := c.simulate(built); err != nil {
+			var txErr *txError
+			if errors.As(err, &txErr) {
+				return nil, txErr
+			}
+			return nil, newTxError(err)
+		}
+	}
+
+	// the gas estimation also rejects a tx bound to revert
+	gasLimit, err := c.gasLimit(opts.From, built)
+	if err != nil {
+		return nil, newTxError(c.withRevertReason(err))
+	}
+	opts.GasLimit = gasLimit
+
+	nonce, err := c.nonces.acquire(c.ctx, c.ethClient().PendingNonceAt)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Nonce = new(big.Int).SetUint64(nonce)
+	var signed *types.Transaction
+	sign := opts.Signer
+	opts.Signer = func(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		tx, err := sign(from, tx)
+		signed = tx
+		return tx, err
+	}
+	tx, err := send(&opts)
+	if err != nil && signed != nil && classifyError(err) == acceptedError {
+		// the node has the tx, which keeps its nonce and is tracked as sent
+		logger.Info("Tx already accepted by the node", "hash", signed.Hash().Hex(), "nonce", nonce, "err", err.Error())
+		tx, err = signed, nil
+	}
+	if err != nil {
+		c.nonces.release(nonce)
+		txErr := newTxError(c.withRevertReason(err))
+		if txErr.category == nonceError {
+			c.nonces.reset()
+		}
+		return nil, txErr
+	}
+	logger.Info("Send tx", "hash", tx.Hash().Hex(), "nonce", nonce, "gas limit", tx.Gas(), "gas price", tx.GasPrice(), "fee cap", tx.GasFeeCap(), "tip cap", tx.GasTipCap())
+
+	return tx, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// pendingNonce is a nonce reader returning a fixed pending nonce
+type pendingNonce uint64
+
+func (p *pendingNonce) read(context.Context, common.Address) (uint64, error) {
+	return uint64(*p), nil
+}
+
+func acquireNonce(t *testing.T, m *nonceManager, pending *pendingNonce) uint64 {
+	t.Helper()
+	nonce, err := m.acquire(context.Background(), pending.read)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	return nonce
+}
+
+func TestNonceManagerReusesReleasedNonce(t *testing.T) {
+	pending := pendingNonce(5)
+	m := newNonceManager(common.HexToAddress("0x01"))
+
+	if n := acquireNonce(t, m, &pending); n != 5 {
+		t.Fatalf("first nonce %d, want 5", n)
+	}
+	if n := acquireNonce(t, m, &pending); n != 6 {
+		t.Fatalf("second nonce %d, want 6", n)
+	}
+	if n := acquireNonce(t, m, &pending); n != 7 {
+		t.Fatalf("third nonce %d, want 7", n)
+	}
+
+	// a gap is filled first, the last nonce is handed back to next
+	m.release(6)
+	m.release(7)
+	if n := acquireNonce(t, m, &pending); n != 6 {
+		t.Fatalf("nonce after release %d, want 6", n)
+	}
+	if n := acquireNonce(t, m, &pending); n != 7 {
+		t.Fatalf("nonce after release %d, want 7", n)
+	}
+}
+
+func TestNonceManagerResyncsAfterAbandon(t *testing.T) {
+	pending := pendingNonce(5)
+	m := newNonceManager(common.HexToAddress("0x01"))
+
+	first := acquireNonce(t, m, &pending)
+	acquireNonce(t, m, &pending)
+	m.confirm(first + 1)
+
+	// the abandoned nonce no longer blocks the resync with the node
+	m.abandon(first)
+	pending = 9
+	if n := acquireNonce(t, m, &pending); n != 9 {
+		t.Fatalf("nonce after abandon %d, want 9", n)
+	}
+	if m.inFlight[first] {
+		t.Fatalf("abandoned nonce %d still in flight", first)
+	}
+}
+
+func TestNonceManagerResyncSkipsInFlight(t *testing.T) {
+	pending := pendingNonce(5)
+	m := newNonceManager(common.HexToAddress("0x01"))
+
+	first := acquireNonce(t, m, &pending)
+	second := acquireNonce(t, m, &pending)
+
+	// the send of first failed, second is not broadcast yet
+	m.release(first)
+	m.reset()
+	if n := acquireNonce(t, m, &pending); n != first {
+		t.Fatalf("nonce after resync %d, want the released %d", n, first)
+	}
+	if n := acquireNonce(t, m, &pending); n == second {
+		t.Fatalf("in flight nonce %d handed out twice", second)
+	}
+
+	// the nonces in flight stay taken even when the node is behind them
+	m = newNonceManager(common.HexToAddress("0x01"))
+	acquireNonce(t, m, &pending)
+	last := acquireNonce(t, m, &pending)
+	m.release(last)
+	m.reset()
+	if n := acquireNonce(t, m, &pending); n != last {
+		t.Fatalf("nonce after resync %d, want %d past the one in flight", n, last)
+	}
+}
+
+func TestWaitForConfirmedSettlesNonceOnError(t *testing.T) {
+	from := common.HexToAddress("0x01")
+	c := &Client{config: defaultConfig(), nonces: newNonceManager(from)}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	rpcCli := rpc.DialInProc(rpc.NewServer())
+	defer rpcCli.Close()
+	c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+	c.heads = newHeadTracker(c)
+	c.heads.update(100)
+
+	pending := pendingNonce(5)
+	nonce := acquireNonce(t, c.nonces, &pending)
+	tx := types.NewTransaction(nonce, common.HexToAddress("0x02"), new(big.Int), 21000, big.NewInt(1), nil)
+
+	c.cancel()
+	if _, err := c.waitForConfirmed(tx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("waitForConfirmed error %v, want %v", err, context.Canceled)
+	}
+	if c.nonces.inFlight[nonce] {
+		t.Fatalf("nonce %d still in flight after the wait failed", nonce)
+	}
+
+	pending = 6
+	if n := acquireNonce(t, c.nonces, &pending); n != 6 {
+		t.Fatalf("nonce after failed wait %d, want the pending nonce 6", n)
+	}
+}
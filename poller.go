@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+const (
+	subscribeSource = "subscribe"
+	pollSource      = "poll"
+)
+
+// pollingMode reports whether broker events are polled by eth_getLogs
+// instead of subscribed, which is chosen by the url of the active endpoint if
+// not configured.
+func (c *Client) pollingMode() bool {
+	switch c.config.Ether.EventSource {
+	case pollSource:
+		return true
+	case subscribeSource:
+		return false
+	}
+
+	u, err := url.Parse(c.endpoints.current().addr)
+	if err != nil {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+func (c *Client) brokerTopics() []common.Hash {
+	return []common.Hash{
+		c.abi.Events["throwInterchainEvent"].ID,
+		c.abi.Events["throwReceiptEvent"].ID,
+	}
+}
+
+// brokerQuery filters the interchain and receipt events of the broker
+func (c *Client) brokerQuery() ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		Addresses: []common.Address{common.HexToAddress(c.config.Ether.ContractAddress)},
+		Topics:    [][]common.Hash{c.brokerTopics()},
+	}
+}
+
+// pollLogs emulates a log subscription of the broker events over eth_getLogs.
+// The last min_confirm blocks are queried again on every poll so that logs
+// re-mined by a reorg are delivered, the confirm queue drops the duplicates.
+func (c *Client) pollLogs(handle func(log types.Log, quit <-chan struct{})) (event.Subscription, error) {
+	head, err := c.heads.refresh()
+	if err != nil {
+		return nil, err
+	}
+	next := head + 1
+	interval := time.Duration(c.config.Ether.PollInterval) * time.Second
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return nil
+			case <-ticker.C:
+			}
+
+			head, err := c.heads.refresh()
+			if err != nil {
+				return err
+			}
+
+			confirmed, err := c.confirmedHeight(head)
+			if err != nil {
+				return err
+			}
+			start := next
+			if confirmed+1 < start {
+				start = confirmed + 1
+			}
+			for start <= head {
+				end := start + c.config.Ether.BlockRange - 1
+				if end > head {
+					end = head
+				}
+				query := c.brokerQuery()
+				query.FromBlock = new(big.Int).SetUint64(start)
+				query.ToBlock = new(big.Int).SetUint64(end)
+				logs, err := c.ethClient().FilterLogs(c.ctx, query)
+				if err != nil {
+					return err
+				}
+				for _, log := range logs {
+					handle(log, quit)
+				}
+				start = end + 1
+				if start > next {
+					next = start
+				}
+			}
+		}
+	}), nil
+}
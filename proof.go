@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/meshplus/pier-client-ethereum/proof"
+)
+
+// receiptCache keeps the receipts of the last proven block, as the events of
+// a block are usually proven one after another
+type receiptCache struct {
+	lock     sync.Mutex
+	hash     common.Hash
+	header   *types.Header
+	receipts types.Receipts
+}
+
+// eventProof builds the receipt proof of the broker event with id. raw is
+// the log of the event, or empty if the event has to be located by id.
+func (c *Client) eventProof(id string, topic common.Hash, raw types.Log) ([]byte, error) {
+	if raw.BlockHash == (common.Hash{}) {
+		var err error
+		if raw, err = c.locateEvent(id, topic); err != nil {
+			return nil, err
+		}
+	} else if err := c.checkpoint.SaveLocation(id, raw); err != nil {
+		logger.Warn("save event location", "id", id, "err", err.Error())
+	}
+
+	header, receipts, err := c.blockReceipts(raw.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	p, err := proof.New(header, receipts, raw.TxIndex, raw.Index)
+	if err != nil {
+		return nil, fmt.Errorf("build proof of %s: %w", id, err)
+	}
+	return p.Encode()
+}
+
+// locateEvent finds the log of the broker event with id, from the recorded
+// location if it is still canonical, otherwise by searching the recent blocks
+func (c *Client) locateEvent(id string, topic common.Hash) (types.Log, error) {
+	loc, err := c.checkpoint.LoadLocation(id)
+	if err != nil {
+		return types.Log{}, err
+	}
+	if loc != nil {
+		hash, err := c.canonicalHash(loc.Height)
+		if err != nil {
+			return types.Log{}, err
+		}
+		if hash == loc.BlockHash {
+			return types.Log{
+				BlockHash:   loc.BlockHash,
+				BlockNumber: loc.Height,
+				TxIndex:     loc.TxIndex,
+				Index:       loc.LogIndex,
+			}, nil
+		}
+	}
+
+	log, err := c.findEvent(id, topic)
+	if err != nil {
+		return types.Log{}, err
+	}
+	if err := c.checkpoint.SaveLocation(id, log); err != nil {
+		logger.Warn("save event location", "id", id, "err", err.Error())
+	}
+	return log, nil
+}
+
+// findEvent searches the broker logs backwards from the head for the event
+// with id. Locations of the events seen by the consumer are recorded, so a
+// search mostly covers a few recent blocks. It stops at start_height, or at
+// the earliest recorded location if start_height is not set, as an event
+// below it has never been consumed.
+func (c *Client) findEvent(id string, topic common.Hash) (types.Log, error) {
+	head, err := c.heads.current()
+	if err != nil {
+		return types.Log{}, err
+	}
+	floor, err := c.searchFloor(head)
+	if err != nil {
+		return types.Log{}, err
+	}
+
+	for end := head; end >= floor; {
+		start := floor
+		if end+1-floor > c.config.Ether.BlockRange {
+			start = end + 1 - c.config.Ether.BlockRange
+		}
+		logs, err := c.ethClient().FilterLogs(c.ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{common.HexToAddress(c.config.Ether.ContractAddress)},
+			Topics:    [][]common.Hash{{topic}},
+		})
+		if err != nil {
+			return types.Log{}, fmt.Errorf("filter logs from %d to %d: %w", start, end, err)
+		}
+		for i := len(logs) - 1; i >= 0; i-- {
+			if !logs[i].Removed && c.logEventID(logs[i]) == id {
+				return logs[i], nil
+			}
+		}
+
+		if start == floor {
+			break
+		}
+		end = start - 1
+	}
+
+	return types.Log{}, fmt.Errorf("event %s not found from block %d to %d", id, floor, head)
+}
+
+// searchFloor returns the lowest block findEvent searches. Without
+// start_height and any recorded location only the latest block range is
+// searched.
+func (c *Client) searchFloor(head uint64) (uint64, error) {
+	if c.config.Ether.StartHeight != 0 {
+		return c.config.Ether.StartHeight, nil
+	}
+	earliest, ok, err := c.checkpoint.EarliestLocation()
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return earliest, nil
+	}
+	if head+1 > c.config.Ether.BlockRange {
+		return head + 1 - c.config.Ether.BlockRange, nil
+	}
+	return 0, nil
+}
+
+// logEventID returns the id of a broker event log, relay and direct brokers
+// share the event signatures
+func (c *Client) logEventID(log types.Log) string {
+	if len(log.Topics) == 0 {
+		return ""
+	}
+	for name, newID := range map[string]func(src, dst string, index uint64) string{
+		"throwInterchainEvent": interchainEventID,
+		"throwReceiptEvent":    receiptEventID,
+	} {
+		if log.Topics[0] != c.abi.Events[name].ID {
+			continue
+		}
+		values, err := c.abi.Unpack(name, log.Data)
+		if err != nil || len(values) < 3 {
+			return ""
+		}
+		index, _ := values[0].(uint64)
+		dst, _ := values[1].(string)
+		src, _ := values[2].(string)
+		return newID(src, dst, index)
+	}
+	return ""
+}
+
+// blockReceipts returns the header and all the receipts of a block
+func (c *Client) blockReceipts(hash common.Hash) (*types.Header, types.Receipts, error) {
+	cache := &c.receipts
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if cache.hash == hash {
+		return cache.header, cache.receipts, nil
+	}
+
+	block, err := c.ethClient().BlockByHash(c.ctx, hash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get block %s: %w", hash.Hex(), err)
+	}
+
+	var receipts types.Receipts
+	if err := c.rpcClient().CallContext(c.ctx, &receipts, "eth_getBlockReceipts", hash); err != nil || len(receipts) != len(block.Transactions()) {
+		// not every node serves eth_getBlockReceipts
+		receipts = make(types.Receipts, len(block.Transactions()))
+		for i, tx := range block.Transactions() {
+			if receipts[i], err = c.ethClient().TransactionReceipt(c.ctx, tx.Hash()); err != nil {
+				return nil, nil, fmt.Errorf("get receipt of %s: %w", tx.Hash().Hex(), err)
+			}
+		}
+	}
+
+	cache.hash, cache.header, cache.receipts = hash, block.Header(), receipts
+	return cache.header, cache.receipts, nil
+}
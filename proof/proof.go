@@ -0,0 +1,293 @@
+// Package proof builds and verifies the inclusion proofs carried by the IBTPs
+// of the ethereum plugin. A proof shows that a broker event was emitted by a
+// successful transaction of an ethereum block.
+//
+// A proof is the RLP encoding of the list
+//
+//	[header, receipt, txIndex, logIndex, nodes]
+//
+// where
+//
+//	header    is the RLP encoded block header
+//	receipt   is the consensus encoding of the receipt, as kept in the receipt trie
+//	txIndex   is the index of the transaction in the block, the receipt trie key is its RLP encoding
+//	logIndex  is the position of the event in the logs of the receipt
+//	nodes     are the receipt trie nodes on the path from the ReceiptHash of the header to the receipt
+//
+// Verify checks a proof on its own, VerifyEvent also checks that the proven
+// broker event is the one the IBTP was built from. Whether the header belongs
+// to the canonical chain is left to the caller, e.g. by comparing its hash
+// with a trusted one.
+package proof
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// ReceiptProof proves a log of a receipt against the receipt root of a block
+type ReceiptProof struct {
+	Header   []byte
+	Receipt  []byte
+	TxIndex  uint64
+	LogIndex uint64
+	Nodes    [][]byte
+}
+
+// Event is a log proven by a ReceiptProof
+type Event struct {
+	Header  *types.Header
+	Receipt *types.Receipt
+	Log     *types.Log
+}
+
+// New builds the proof of the log with block level index logIndex, emitted by
+// the transaction at txIndex. receipts must be all the receipts of the block.
+func New(header *types.Header, receipts types.Receipts, txIndex uint, logIndex uint) (*ReceiptProof, error) {
+	if int(txIndex) >= len(receipts) {
+		return nil, fmt.Errorf("tx index %d out of %d receipts", txIndex, len(receipts))
+	}
+	pos := -1
+	for i, log := range receipts[txIndex].Logs {
+		if log.Index == logIndex {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		return nil, fmt.Errorf("log %d not in receipt of tx %d", logIndex, txIndex)
+	}
+
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		buf    bytes.Buffer
+		target []byte
+	)
+	for i := range receipts {
+		buf.Reset()
+		receipts.EncodeIndex(i, &buf)
+		key, err := rlp.EncodeToBytes(uint(i))
+		if err != nil {
+			return nil, err
+		}
+		value := common.CopyBytes(buf.Bytes())
+		if err := tr.TryUpdate(key, value); err != nil {
+			return nil, err
+		}
+		if i == int(txIndex) {
+			target = value
+		}
+	}
+	if root := tr.Hash(); root != header.ReceiptHash {
+		return nil, fmt.Errorf("receipt root %s mismatches header %s", root.Hex(), header.ReceiptHash.Hex())
+	}
+
+	nodes := memorydb.New()
+	key, err := rlp.EncodeToBytes(txIndex)
+	if err != nil {
+		return nil, err
+	}
+	if err := tr.Prove(key, 0, nodes); err != nil {
+		return nil, fmt.Errorf("prove receipt %d: %w", txIndex, err)
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ReceiptProof{
+		Header:   enc,
+		Receipt:  target,
+		TxIndex:  uint64(txIndex),
+		LogIndex: uint64(pos),
+	}
+	it := nodes.NewIterator(nil, nil)
+	for it.Next() {
+		p.Nodes = append(p.Nodes, common.CopyBytes(it.Value()))
+	}
+	it.Release()
+
+	return p, nil
+}
+
+// Encode serializes the proof in the format documented by the package
+func (p *ReceiptProof) Encode() ([]byte, error) {
+	return rlp.EncodeToBytes(p)
+}
+
+// Decode parses a proof serialized by Encode
+func Decode(data []byte) (*ReceiptProof, error) {
+	p := &ReceiptProof{}
+	if err := rlp.DecodeBytes(data, p); err != nil {
+		return nil, fmt.Errorf("decode receipt proof: %w", err)
+	}
+	return p, nil
+}
+
+// Verify checks that the proven receipt is in the receipt trie of the header
+// and belongs to a successful transaction, and returns the proven log
+func Verify(data []byte) (*Event, error) {
+	p, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &types.Header{}
+	if err := rlp.DecodeBytes(p.Header, header); err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+
+	nodes := memorydb.New()
+	for _, node := range p.Nodes {
+		if err := nodes.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, err
+		}
+	}
+	key, err := rlp.EncodeToBytes(p.TxIndex)
+	if err != nil {
+		return nil, err
+	}
+	value, err := trie.VerifyProof(header.ReceiptHash, key, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt proof: %w", err)
+	}
+	if value == nil || !bytes.Equal(value, p.Receipt) {
+		return nil, fmt.Errorf("receipt %d not in receipt root %s", p.TxIndex, header.ReceiptHash.Hex())
+	}
+
+	receipt, err := decodeReceipt(p.Receipt)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("tx %d of block %d failed", p.TxIndex, header.Number)
+	}
+	if p.LogIndex >= uint64(len(receipt.Logs)) {
+		return nil, fmt.Errorf("log %d out of %d logs", p.LogIndex, len(receipt.Logs))
+	}
+
+	log := receipt.Logs[p.LogIndex]
+	log.BlockNumber = header.Number.Uint64()
+	log.BlockHash = header.Hash()
+	log.TxIndex = uint(p.TxIndex)
+
+	return &Event{Header: header, Receipt: receipt, Log: log}, nil
+}
+
+// VerifyEvent verifies the proof carried by ibtp, and that the proven log is
+// the broker event the ibtp was built from: emitted by contract, and with the
+// index, source, destination and payload hash of the ibtp. brokerABI has to
+// declare the throwInterchainEvent and throwReceiptEvent of the broker.
+func VerifyEvent(ibtp *pb.IBTP, contract common.Address, brokerABI abi.ABI) (*Event, error) {
+	ev, err := Verify(ibtp.Proof)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Log.Address != contract {
+		return nil, fmt.Errorf("log emitted by %s instead of %s", ev.Log.Address.Hex(), contract.Hex())
+	}
+
+	name := "throwReceiptEvent"
+	if ibtp.Type == pb.IBTP_INTERCHAIN {
+		name = "throwInterchainEvent"
+	}
+	event, ok := brokerABI.Events[name]
+	if !ok {
+		return nil, fmt.Errorf("event %s not in broker abi", name)
+	}
+	if len(ev.Log.Topics) == 0 || ev.Log.Topics[0] != event.ID {
+		return nil, fmt.Errorf("log is not event %s", name)
+	}
+	fields := make(map[string]interface{})
+	if err := brokerABI.UnpackIntoMap(fields, name, ev.Log.Data); err != nil {
+		return nil, fmt.Errorf("unpack %s: %w", name, err)
+	}
+
+	index, _ := fields["index"].(uint64)
+	src, _ := fields["srcFullID"].(string)
+	dst, _ := fields["dstFullID"].(string)
+	hash, _ := fields["hash"].([32]byte)
+	if index != ibtp.Index || src != ibtp.From || dst != ibtp.To {
+		return nil, fmt.Errorf("event %s-%s-%d mismatches ibtp %s-%s-%d", src, dst, index, ibtp.From, ibtp.To, ibtp.Index)
+	}
+	if typ, ok := fields["typ"].(uint64); ok && typ != uint64(ibtp.Type) {
+		return nil, fmt.Errorf("event type %d mismatches ibtp type %s", typ, ibtp.Type)
+	}
+	if err := verifyPayload(ibtp, common.Hash(hash)); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// verifyPayload checks that the payload of ibtp carries the hash of the event,
+// and that a plain content hashes to it the way the broker does
+func verifyPayload(ibtp *pb.IBTP, hash common.Hash) error {
+	payload := &pb.Payload{}
+	if err := payload.Unmarshal(ibtp.Payload); err != nil {
+		return fmt.Errorf("unmarshal payload: %w", err)
+	}
+	if !bytes.Equal(payload.Hash, hash[:]) {
+		return fmt.Errorf("payload hash %x mismatches event hash %s", payload.Hash, hash.Hex())
+	}
+	if payload.Encrypted {
+		return nil
+	}
+
+	var packed []byte
+	if ibtp.Type == pb.IBTP_INTERCHAIN {
+		content := &pb.Content{}
+		if err := content.Unmarshal(payload.Content); err != nil {
+			return fmt.Errorf("unmarshal content: %w", err)
+		}
+		packed = append(packed, content.Func...)
+		for _, arg := range content.Args {
+			packed = append(packed, arg...)
+		}
+	} else {
+		result := &pb.Result{}
+		if err := result.Unmarshal(payload.Content); err != nil {
+			return fmt.Errorf("unmarshal result: %w", err)
+		}
+		for _, res := range result.Data {
+			for _, data := range res.Data {
+				packed = append(packed, data...)
+			}
+		}
+	}
+	if !bytes.Equal(crypto.Keccak256(packed), hash[:]) {
+		return fmt.Errorf("payload content mismatches event hash %s", hash.Hex())
+	}
+	return nil
+}
+
+// decodeReceipt parses the consensus encoding of a receipt. Typed receipts
+// are wrapped in an rlp string, the form Receipt.DecodeRLP expects.
+func decodeReceipt(enc []byte) (*types.Receipt, error) {
+	if len(enc) == 0 {
+		return nil, fmt.Errorf("empty receipt")
+	}
+	if enc[0] < 0x80 {
+		var err error
+		if enc, err = rlp.EncodeToBytes(enc); err != nil {
+			return nil, err
+		}
+	}
+
+	receipt := &types.Receipt{}
+	if err := rlp.DecodeBytes(enc, receipt); err != nil {
+		return nil, fmt.Errorf("decode receipt: %w", err)
+	}
+	return receipt, nil
+}
@@ -0,0 +1,188 @@
+package proof
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// testBrokerABI declares the broker events which IBTPs are built from
+const testBrokerABI = `[
+{"anonymous":false,"inputs":[{"indexed":false,"internalType":"uint64","name":"index","type":"uint64"},{"indexed":false,"internalType":"string","name":"dstFullID","type":"string"},{"indexed":false,"internalType":"string","name":"srcFullID","type":"string"},{"indexed":false,"internalType":"string","name":"func","type":"string"},{"indexed":false,"internalType":"bytes[]","name":"args","type":"bytes[]"},{"indexed":false,"internalType":"bytes32","name":"hash","type":"bytes32"},{"indexed":false,"internalType":"string[]","name":"group","type":"string[]"}],"name":"throwInterchainEvent","type":"event"},
+{"anonymous":false,"inputs":[{"indexed":false,"internalType":"uint64","name":"index","type":"uint64"},{"indexed":false,"internalType":"string","name":"dstFullID","type":"string"},{"indexed":false,"internalType":"string","name":"srcFullID","type":"string"},{"indexed":false,"internalType":"uint64","name":"typ","type":"uint64"},{"indexed":false,"internalType":"bytes[][]","name":"results","type":"bytes[][]"},{"indexed":false,"internalType":"bytes32","name":"hash","type":"bytes32"},{"indexed":false,"internalType":"bool[]","name":"multiStatus","type":"bool[]"}],"name":"throwReceiptEvent","type":"event"}
+]`
+
+const (
+	testSrc = "1356:chain0:0xa"
+	testDst = "1356:chain1:0xb"
+)
+
+var testBroker = common.HexToAddress("0x857133c5c69e6ce66f7ad46f200b9b3573e77582")
+
+// testBlock is a block of three txs: a transfer, a tx emitting an interchain
+// and a receipt event of the broker, and a failed tx
+type testBlock struct {
+	abi      abi.ABI
+	header   *types.Header
+	receipts types.Receipts
+}
+
+func newTestBlock(t *testing.T) *testBlock {
+	t.Helper()
+	brokerABI, err := abi.JSON(strings.NewReader(testBrokerABI))
+	if err != nil {
+		t.Fatalf("parse abi: %v", err)
+	}
+	b := &testBlock{abi: brokerABI}
+
+	interchain := b.log(t, "throwInterchainEvent", uint64(1), testDst, testSrc, "interchainCharge", [][]byte{[]byte("alice"), []byte("10")},
+		crypto.Keccak256Hash([]byte("interchainCharge"), []byte("alice"), []byte("10")), []string{})
+	receipt := b.log(t, "throwReceiptEvent", uint64(1), testDst, testSrc, uint64(pb.IBTP_RECEIPT_SUCCESS), [][][]byte{{[]byte("ok")}},
+		crypto.Keccak256Hash([]byte("ok")), []bool{true})
+	failed := b.log(t, "throwInterchainEvent", uint64(2), testDst, testSrc, "", [][]byte{}, common.Hash{}, []string{})
+	interchain.Index, receipt.Index, failed.Index = 0, 1, 2
+
+	b.receipts = types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000},
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 90000, Logs: []*types.Log{interchain, receipt}},
+		{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 120000, Logs: []*types.Log{failed}},
+	}
+	for _, r := range b.receipts {
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+	}
+	b.header = &types.Header{
+		Number:      big.NewInt(10),
+		ReceiptHash: types.DeriveSha(b.receipts, trie.NewStackTrie(nil)),
+	}
+	return b
+}
+
+func (b *testBlock) log(t *testing.T, name string, args ...interface{}) *types.Log {
+	t.Helper()
+	data, err := b.abi.Events[name].Inputs.NonIndexed().Pack(args...)
+	if err != nil {
+		t.Fatalf("pack %s: %v", name, err)
+	}
+	return &types.Log{Address: testBroker, Topics: []common.Hash{b.abi.Events[name].ID}, Data: data}
+}
+
+func interchainIBTP(t *testing.T, args ...[]byte) *pb.IBTP {
+	t.Helper()
+	content, err := (&pb.Content{Func: "interchainCharge", Args: args}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := crypto.Keccak256([]byte("interchainCharge"), []byte("alice"), []byte("10"))
+	payload, err := (&pb.Payload{Content: content, Hash: hash}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pb.IBTP{From: testSrc, To: testDst, Index: 1, Type: pb.IBTP_INTERCHAIN, Payload: payload}
+}
+
+func receiptIBTP(t *testing.T) *pb.IBTP {
+	t.Helper()
+	content, err := (&pb.Result{Data: []*pb.ResultRes{{Data: [][]byte{[]byte("ok")}}}, MultiStatus: []bool{true}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := (&pb.Payload{Content: content, Hash: crypto.Keccak256([]byte("ok"))}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pb.IBTP{From: testSrc, To: testDst, Index: 1, Type: pb.IBTP_RECEIPT_SUCCESS, Payload: payload}
+}
+
+func TestVerifyEvent(t *testing.T) {
+	block := newTestBlock(t)
+	alice := []byte("alice")
+
+	for _, test := range []struct {
+		name     string
+		txIndex  uint
+		logIndex uint
+		ibtp     *pb.IBTP
+		tamper   func(p *ReceiptProof)
+		contract common.Address
+		wantErr  string
+	}{
+		{name: "valid interchain", txIndex: 1, logIndex: 0, ibtp: interchainIBTP(t, alice, []byte("10"))},
+		{name: "valid receipt", txIndex: 1, logIndex: 1, ibtp: receiptIBTP(t)},
+		{name: "tampered receipt", txIndex: 1, logIndex: 0, ibtp: interchainIBTP(t, alice, []byte("10")),
+			tamper:  func(p *ReceiptProof) { p.Receipt[len(p.Receipt)-1] ^= 1 },
+			wantErr: "not in receipt root"},
+		{name: "tampered trie node", txIndex: 1, logIndex: 0, ibtp: interchainIBTP(t, alice, []byte("10")),
+			tamper:  func(p *ReceiptProof) { p.Nodes[0][len(p.Nodes[0])-1] ^= 1 },
+			wantErr: "invalid receipt proof"},
+		{name: "receipt of another tx", txIndex: 1, logIndex: 0, ibtp: interchainIBTP(t, alice, []byte("10")),
+			tamper:  func(p *ReceiptProof) { p.TxIndex = 0 },
+			wantErr: "not in receipt root"},
+		{name: "failed tx", txIndex: 2, logIndex: 2, ibtp: interchainIBTP(t, alice, []byte("10")),
+			wantErr: "failed"},
+		{name: "other contract", txIndex: 1, logIndex: 0, ibtp: interchainIBTP(t, alice, []byte("10")),
+			contract: common.HexToAddress("0x01"),
+			wantErr:  "emitted by"},
+		{name: "other event", txIndex: 1, logIndex: 1, ibtp: interchainIBTP(t, alice, []byte("10")),
+			wantErr: "is not event"},
+		{name: "mismatched index", txIndex: 1, logIndex: 0, ibtp: func() *pb.IBTP {
+			ibtp := interchainIBTP(t, alice, []byte("10"))
+			ibtp.Index = 2
+			return ibtp
+		}(), wantErr: "mismatches ibtp"},
+		{name: "mismatched destination", txIndex: 1, logIndex: 1, ibtp: func() *pb.IBTP {
+			ibtp := receiptIBTP(t)
+			ibtp.To = "1356:chain2:0xb"
+			return ibtp
+		}(), wantErr: "mismatches ibtp"},
+		{name: "mismatched payload", txIndex: 1, logIndex: 0, ibtp: interchainIBTP(t, alice, []byte("1000")),
+			wantErr: "payload content mismatches"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := New(block.header, block.receipts, test.txIndex, test.logIndex)
+			if err != nil {
+				t.Fatalf("new proof: %v", err)
+			}
+			if test.tamper != nil {
+				test.tamper(p)
+			}
+			if test.ibtp.Proof, err = p.Encode(); err != nil {
+				t.Fatalf("encode proof: %v", err)
+			}
+			contract := test.contract
+			if contract == (common.Address{}) {
+				contract = testBroker
+			}
+
+			ev, err := VerifyEvent(test.ibtp, contract, block.abi)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verify: %v", err)
+				}
+				if ev.Log.BlockHash != block.header.Hash() || ev.Log.TxIndex != test.txIndex {
+					t.Fatalf("proven log at %s tx %d, want %s tx %d", ev.Log.BlockHash.Hex(), ev.Log.TxIndex, block.header.Hash().Hex(), test.txIndex)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("verify error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRejectsWrongReceipts(t *testing.T) {
+	block := newTestBlock(t)
+	if _, err := New(block.header, block.receipts[:2], 1, 0); err == nil || !strings.Contains(err.Error(), "receipt root") {
+		t.Fatalf("proof over partial receipts: %v, want a receipt root mismatch", err)
+	}
+	if _, err := New(block.header, block.receipts, 0, 0); err == nil {
+		t.Fatal("proof of a log not in the receipt")
+	}
+}
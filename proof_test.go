@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// searchNode is a logsNode at a fixed head
+type searchNode struct {
+	logsNode
+	head uint64
+}
+
+func (n *searchNode) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(n.head)
+}
+
+func TestFindEventStopsAtFloor(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		startHeight uint64
+		location    uint64
+		wantQueries []uint64
+	}{
+		{name: "start height", startHeight: 55, wantQueries: []uint64{91, 81, 71, 61, 55}},
+		{name: "earliest location", location: 75, wantQueries: []uint64{91, 81, 75}},
+		{name: "nothing recorded", wantQueries: []uint64{91}},
+		{name: "start height above head", startHeight: 120},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			node := &searchNode{head: 100}
+			server := rpc.NewServer()
+			if err := server.RegisterName("eth", node); err != nil {
+				t.Fatalf("register fake node: %v", err)
+			}
+			rpcCli := rpc.DialInProc(server)
+			defer rpcCli.Close()
+
+			store, err := NewCheckpointStore(t.TempDir(), "0x01")
+			if err != nil {
+				t.Fatalf("open checkpoint store: %v", err)
+			}
+			defer store.Close()
+			if test.location != 0 {
+				if err := store.SaveLocation("interchain-known", types.Log{BlockNumber: test.location}); err != nil {
+					t.Fatalf("save location: %v", err)
+				}
+			}
+
+			config := defaultConfig()
+			config.Ether.BlockRange = 10
+			config.Ether.StartHeight = test.startHeight
+			c := &Client{ctx: context.Background(), config: config, checkpoint: store}
+			c.heads = newHeadTracker(c)
+			c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+
+			_, err = c.findEvent("interchain-unknown", common.Hash{})
+			if err == nil || !strings.Contains(err.Error(), "not found") {
+				t.Fatalf("find event error %v, want not found", err)
+			}
+			if len(node.queries) != len(test.wantQueries) {
+				t.Fatalf("queried from %v, want %v", node.queries, test.wantQueries)
+			}
+			for i := range node.queries {
+				if node.queries[i] != test.wantQueries[i] {
+					t.Fatalf("queried from %v, want %v", node.queries, test.wantQueries)
+				}
+			}
+		})
+	}
+}
@@ -14,7 +14,7 @@ import (
 //	return generateReceipt(original.From, original.To, original.Index, data, typ, payload.Encrypted, multiStatus)
 //}
 
-func generateReceipt(from, to string, idx uint64, data [][][]byte, typ uint64, encrypt bool, multiStatus []bool) (*pb.IBTP, error) {
+func generateReceipt(from, to string, idx uint64, data [][][]byte, typ uint64, encrypt bool, multiStatus []bool, proof []byte) (*pb.IBTP, error) {
 	//result := &pb.Result{Data: data}
 	var result []*pb.ResultRes
 	for _, s := range data {
@@ -51,7 +51,7 @@ func generateReceipt(from, to string, idx uint64, data [][][]byte, typ uint64, e
 		Index:         idx,
 		Type:          pb.IBTP_Type(typ),
 		TimeoutHeight: 0,
-		Proof:         []byte("1"),
+		Proof:         proof,
 		Payload:       pd,
 	}, nil
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+)
+
+// pairProgress records which interchain indices of a service pair have been
+// pushed to pier
+type pairProgress struct {
+	emitted  uint64
+	ahead    map[uint64]bool
+	lastMeta uint64
+	seen     bool
+}
+
+func (pp *pairProgress) advance(emitted uint64) {
+	if emitted > pp.emitted {
+		pp.emitted = emitted
+	}
+	for idx := range pp.ahead {
+		if idx <= pp.emitted {
+			delete(pp.ahead, idx)
+		}
+	}
+	for pp.ahead[pp.emitted+1] {
+		delete(pp.ahead, pp.emitted+1)
+		pp.emitted++
+	}
+}
+
+type outProgress struct {
+	pairs map[string]*pairProgress
+	lock  sync.Mutex
+}
+
+func newOutProgress() *outProgress {
+	return &outProgress{pairs: make(map[string]*pairProgress)}
+}
+
+func (p *outProgress) pair(servicePair string) *pairProgress {
+	pp, ok := p.pairs[servicePair]
+	if !ok {
+		pp = &pairProgress{ahead: make(map[uint64]bool)}
+		p.pairs[servicePair] = pp
+	}
+	return pp
+}
+
+func (p *outProgress) markEmitted(servicePair string, index uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pp := p.pair(servicePair)
+	if index <= pp.emitted {
+		return
+	}
+	pp.ahead[index] = true
+	pp.advance(pp.emitted)
+}
+
+// missing returns the indices up to the outer meta of the previous round
+// which have not been emitted, and records meta for the next round. The
+// indices before the first observed meta are assumed to be handled.
+func (p *outProgress) missing(servicePair string, meta uint64) []uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pp := p.pair(servicePair)
+	if !pp.seen {
+		baseline := meta
+		for idx := range pp.ahead {
+			if idx-1 < baseline {
+				baseline = idx - 1
+			}
+		}
+		pp.advance(baseline)
+		pp.lastMeta = meta
+		pp.seen = true
+		return nil
+	}
+
+	var indices []uint64
+	for idx := pp.emitted + 1; idx <= pp.lastMeta; idx++ {
+		if !pp.ahead[idx] {
+			indices = append(indices, idx)
+		}
+	}
+	pp.lastMeta = meta
+	return indices
+}
+
+// startReconciler periodically compares the emitted interchain indices with
+// the outer meta of the broker and emits the IBTPs which were missed.
+func (c *Client) startReconciler() {
+	if c.config.Ether.ReconcileInterval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(c.config.Ether.ReconcileInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.reconcile(); err != nil {
+				logger.Warn("reconcile outer meta", "err", err.Error())
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) reconcile() error {
+	head, err := c.heads.current()
+	if err != nil {
+		return err
+	}
+	// only the events on confirmed blocks are expected to be emitted, and
+	// only once the consumer got past them, as an event still backfilled or
+	// retried by the consumer would be emitted twice
+	confirmed, err := c.confirmedHeight(head)
+	if err != nil {
+		return err
+	}
+	if consumed := atomic.LoadUint64(&c.consumed); consumed < confirmed {
+		confirmed = consumed
+	}
+	if confirmed == 0 {
+		return nil
+	}
+	opts := &bind.CallOpts{
+		BlockNumber: new(big.Int).SetUint64(confirmed),
+		Context:     c.ctx,
+	}
+
+	meta, err := c.getMeta(c.broker.getOuterMeta, opts)
+	if err != nil {
+		return err
+	}
+
+	for servicePair, index := range meta {
+		var recovered []uint64
+		for _, idx := range c.outProgress.missing(servicePair, index) {
+			ibtp, err := c.GetOutMessage(servicePair, idx)
+			if err != nil {
+				logger.Warn("rebuild missing IBTP", "servicePair", servicePair, "index", idx, "err", err.Error())
+				break
+			}
+			c.eventC <- ibtp
+			c.outProgress.markEmitted(servicePair, idx)
+			recovered = append(recovered, idx)
+		}
+		if len(recovered) != 0 {
+			c.recovered += uint64(len(recovered))
+			logger.Warn("Recovered missing IBTPs", "servicePair", servicePair, "indices", recovered, "total recovered", c.recovered)
+		}
+	}
+
+	return nil
+}
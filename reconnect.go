@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	reconnectMinBackoff = time.Second
+	reconnectMaxBackoff = time.Minute
+)
+
+// redial fails the client over to the best endpoint after the connection
+// to the active one broke, which may be the same endpoint once it answers
+func (c *Client) redial() error {
+	c.endpoints.current().fail(errConnectionLost)
+	c.endpoints.probeAll(c.ctx)
+	e := c.endpoints.best()
+	if e == nil {
+		return errNoEndpoint
+	}
+	return c.useEndpoint(e)
+}
+
+// reconnect redials the ethereum node with exponential backoff until resume
+// succeeds on the new connection or the client is stopped
+func (c *Client) reconnect(resume func() error) error {
+	backoff := reconnectMinBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		err := c.redial()
+		if err == nil {
+			err = resume()
+		}
+		if err == nil {
+			total := atomic.AddUint64(&c.reconnects, 1)
+			logger.Info("Reconnected to ethereum node", "addr", c.endpoints.current().addr, "attempt", attempt, "total reconnects", total)
+			return nil
+		}
+		logger.Warn("Reconnect to ethereum node", "addr", c.endpoints.current().addr, "attempt", attempt, "err", err.Error())
+
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errorSelector = string(crypto.Keccak256([]byte("Error(string)"))[:4])
+	panicSelector = string(crypto.Keccak256([]byte("Panic(uint256)"))[:4])
+)
+
+// panicReasons are the descriptions of solidity panic codes
+var panicReasons = map[uint64]string{
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to invalid internal function",
+}
+
+// customError is an error declared in the broker ABI
+type customError struct {
+	name   string
+	inputs abi.Arguments
+}
+
+// parseCustomErrors collects the custom errors of an ABI by selector, the
+// errors with inputs which can't be decoded are skipped.
+func parseCustomErrors(abiJSON string) (map[string]*customError, error) {
+	var entries []struct {
+		Type   string `json:"type"`
+		Name   string `json:"name"`
+		Inputs []struct {
+			Name         string `json:"name"`
+			Type         string `json:"type"`
+			InternalType string `json:"internalType"`
+		} `json:"inputs"`
+	}
+	if err := json.Unmarshal([]byte(abiJSON), &entries); err != nil {
+		return nil, fmt.Errorf("unmarshal abi: %w", err)
+	}
+
+	errs := make(map[string]*customError)
+	for _, entry := range entries {
+		if entry.Type != "error" {
+			continue
+		}
+
+		var (
+			inputs    abi.Arguments
+			typeNames []string
+			valid     = true
+		)
+		for _, input := range entry.Inputs {
+			typ, err := abi.NewType(input.Type, input.InternalType, nil)
+			if err != nil {
+				valid = false
+				break
+			}
+			inputs = append(inputs, abi.Argument{Name: input.Name, Type: typ})
+			typeNames = append(typeNames, input.Type)
+		}
+		if !valid {
+			continue
+		}
+
+		sig := fmt.Sprintf("%s(%s)", entry.Name, strings.Join(typeNames, ","))
+		errs[string(crypto.Keccak256([]byte(sig))[:4])] = &customError{name: entry.Name, inputs: inputs}
+	}
+
+	return errs, nil
+}
+
+// decodeRevert decodes Error(string), Panic(uint256) and the custom errors
+// of the broker from revert data
+func (c *Client) decodeRevert(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+
+	selector, payload := string(data[:4]), data[4:]
+	switch selector {
+	case errorSelector:
+		typ, _ := abi.NewType("string", "", nil)
+		vals, err := abi.Arguments{{Type: typ}}.Unpack(payload)
+		if err != nil || len(vals) != 1 {
+			return "", false
+		}
+		reason, ok := vals[0].(string)
+		return reason, ok
+	case panicSelector:
+		typ, _ := abi.NewType("uint256", "", nil)
+		vals, err := abi.Arguments{{Type: typ}}.Unpack(payload)
+		if err != nil || len(vals) != 1 {
+			return "", false
+		}
+		code, ok := vals[0].(interface{ Uint64() uint64 })
+		if !ok {
+			return "", false
+		}
+		if desc, ok := panicReasons[code.Uint64()]; ok {
+			return fmt.Sprintf("panic 0x%x: %s", code.Uint64(), desc), true
+		}
+		return fmt.Sprintf("panic 0x%x", code.Uint64()), true
+	}
+
+	customErr, ok := c.customErrors[selector]
+	if !ok {
+		return "", false
+	}
+	vals, err := customErr.inputs.Unpack(payload)
+	if err != nil {
+		return customErr.name, true
+	}
+	args := make([]string, len(vals))
+	for i, val := range vals {
+		args[i] = fmt.Sprintf("%v", val)
+	}
+	return fmt.Sprintf("%s(%s)", customErr.name, strings.Join(args, ", ")), true
+}
+
+// errorReason returns the decoded revert reason carried by a JSON-RPC error
+func (c *Client) errorReason(err error) (string, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return "", false
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return "", false
+	}
+	data, decodeErr := hexutil.Decode(hexData)
+	if decodeErr != nil {
+		return "", false
+	}
+
+	return c.decodeRevert(data)
+}
+
+// isCallReverted tells whether err is an eth_call that reverted, as opposed
+// to a failure to reach the node. Every JSON-RPC error is a DataError, only
+// a revert carries data.
+func isCallReverted(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) && dataErr.ErrorData() != nil {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "execution reverted") || strings.Contains(msg, "invalid opcode")
+}
+
+// withRevertReason appends the decoded revert reason to err if the message
+// of err does not carry it yet
+func (c *Client) withRevertReason(err error) error {
+	reason, ok := c.errorReason(err)
+	if !ok || strings.Contains(err.Error(), reason) {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, reason)
+}
+
+// revertReason replays a failed tx by eth_call on the state before its
+// block to find out why it reverted
+func (c *Client) revertReason(receipt *types.Receipt) (string, error) {
+	if receipt.BlockNumber == nil || receipt.BlockNumber.Sign() == 0 {
+		return "", fmt.Errorf("tx %s has no parent state to replay on", receipt.TxHash.Hex())
+	}
+	tx, _, err := c.ethClient().TransactionByHash(c.ctx, receipt.TxHash)
+	if err != nil {
+		return "", fmt.Errorf("get tx %s: %w", receipt.TxHash.Hex(), err)
+	}
+
+	msg := ethereum.CallMsg{
+		From:  c.transactOpts().From,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	// the state after the block holds the effects of the tx itself, the
+	// replay runs on the parent state
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	_, err = c.ethClient().CallContract(c.ctx, msg, parent)
+	if err == nil {
+		return "", fmt.Errorf("tx %s does not revert on replay", receipt.TxHash.Hex())
+	}
+	if reason, ok := c.errorReason(err); ok {
+		return reason, nil
+	}
+
+	return err.Error(), nil
+}
+
+// failureMessage describes a failed tx by the given message and the revert
+// reason if it can be found
+func (c *Client) failureMessage(msg string, receipt *types.Receipt) string {
+	reason, err := c.revertReason(receipt)
+	if err != nil {
+		logger.Warn("Can't find revert reason", "hash", receipt.TxHash.Hex(), "error", err.Error())
+		return msg
+	}
+
+	logger.Warn("Tx reverted", "hash", receipt.TxHash.Hex(), "reason", reason)
+	return fmt.Sprintf("%s: %s", msg, reason)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// replayNode serves a failed tx and reverts its replay, recording the block
+// of every replay
+type replayNode struct {
+	tx     *types.Transaction
+	data   []byte
+	blocks []string
+}
+
+func (n *replayNode) GetTransactionByHash(common.Hash) *types.Transaction {
+	return n.tx
+}
+
+func (n *replayNode) Call(_ map[string]interface{}, block string) (hexutil.Bytes, error) {
+	n.blocks = append(n.blocks, block)
+	return nil, &revertDataError{data: n.data}
+}
+
+func TestRevertReasonReplaysOnParentState(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.HomesteadSigner{}
+	tx, err := types.SignTx(types.NewTransaction(3, common.HexToAddress("0x02"), new(big.Int), 300000, big.NewInt(1), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &replayNode{tx: tx, data: errorData(t, "index not match")}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("register fake node: %v", err)
+	}
+	rpcCli := rpc.DialInProc(server)
+	defer rpcCli.Close()
+
+	c := &Client{ctx: context.Background()}
+	c.broker = &relayBroker{session: &BrokerSession{TransactOpts: bind.TransactOpts{From: crypto.PubkeyToAddress(key.PublicKey)}}}
+	c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+
+	reason, err := c.revertReason(&types.Receipt{TxHash: tx.Hash(), BlockNumber: big.NewInt(10)})
+	if err != nil {
+		t.Fatalf("revert reason: %v", err)
+	}
+	if reason != "index not match" {
+		t.Fatalf("reason %q, want %q", reason, "index not match")
+	}
+	if len(node.blocks) != 1 || node.blocks[0] != "0x9" {
+		t.Fatalf("replayed at %v, want the parent block 0x9", node.blocks)
+	}
+
+	if _, err := c.revertReason(&types.Receipt{TxHash: tx.Hash(), BlockNumber: big.NewInt(0)}); err == nil {
+		t.Fatal("replayed a tx of the genesis block")
+	}
+	if len(node.blocks) != 1 {
+		t.Fatalf("replayed at %v, want no replay for the genesis block", node.blocks)
+	}
+}
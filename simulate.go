@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// buildGasLimit keeps bind from estimating the gas of a tx that is only built
+const buildGasLimit = 1
+
+var errTxBuilt = errors.New("tx built")
+
+// buildTx runs send without broadcasting anything and returns the unsigned
+// tx it would have sent
+func buildTx(opts bind.TransactOpts, send func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	var built *types.Transaction
+	opts.Signer = func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
+		built = tx
+		return nil, errTxBuilt
+	}
+	if opts.Nonce == nil {
+		opts.Nonce = new(big.Int)
+	}
+	if opts.GasLimit == 0 {
+		opts.GasLimit = buildGasLimit
+	}
+
+	if _, err := send(&opts); !errors.Is(err, errTxBuilt) {
+		if err == nil {
+			err = fmt.Errorf("tx was sent while building it")
+		}
+		return nil, err
+	}
+	return built, nil
+}
+
+// simulate checks tx against the pending state for the failures the broker
+// records without reverting, which neither eth_call nor the gas estimation
+// report. A reverting tx is caught by the gas estimation, which runs on the
+// pending state too, and the multi-signatures are checked before the send.
+// What is left is an interchain call to a service off the local white list.
+func (c *Client) simulate(tx *types.Transaction) error {
+	list, ok := c.broker.(whiteList)
+	if !ok || len(tx.Data()) < 4 {
+		return nil
+	}
+	method, err := c.gas.abi.MethodById(tx.Data()[:4])
+	if err != nil {
+		return nil
+	}
+	if method.RawName != "invokeInterchain" && method.RawName != "invokeMultiInterchain" {
+		return nil
+	}
+
+	values, err := method.Inputs.Unpack(tx.Data()[4:])
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", method.RawName, err)
+	}
+	destAddr, _ := values[1].(string)
+	listed, err := list.isWhiteListed(&bind.CallOpts{Pending: true, Context: c.ctx}, common.HexToAddress(destAddr))
+	if err != nil {
+		return fmt.Errorf("simulate %s: get local white list: %w", method.RawName, err)
+	}
+	if !listed {
+		// the broker would fail the IBTP the same way on every attempt
+		return &txError{
+			category: revertError,
+			err:      fmt.Errorf("simulate %s: dest address %s is not in local white list", method.RawName, destAddr),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// whiteListNode answers getLocalWhiteList on the pending state
+type whiteListNode struct {
+	listed bool
+	blocks []string
+}
+
+func (n *whiteListNode) Call(_ map[string]interface{}, block string) hexutil.Bytes {
+	n.blocks = append(n.blocks, block)
+	ret := make([]byte, 32)
+	if n.listed {
+		ret[31] = 1
+	}
+	return ret
+}
+
+func TestSimulateChecksWhiteList(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		listed  bool
+		wantErr string
+	}{
+		{name: "white listed", listed: true},
+		{name: "not white listed", wantErr: "not in local white list"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			node := &whiteListNode{listed: test.listed}
+			server := rpc.NewServer()
+			if err := server.RegisterName("eth", node); err != nil {
+				t.Fatalf("register fake node: %v", err)
+			}
+			rpcCli := rpc.DialInProc(server)
+			defer rpcCli.Close()
+
+			broker, err := newRelayBroker(common.HexToAddress("0x01"), ethclient.NewClient(rpcCli), &bind.TransactOpts{})
+			if err != nil {
+				t.Fatalf("new broker: %v", err)
+			}
+			gas, err := newGasPolicy(Gas{}, BrokerABI)
+			if err != nil {
+				t.Fatalf("new gas policy: %v", err)
+			}
+			c := &Client{ctx: context.Background(), broker: broker, gas: gas}
+
+			data, err := gas.abi.Pack("invokeInterchain", "1356:chain0:0xa", "0x000000000000000000000000000000000000000b", uint64(1), uint64(0),
+				"interchainCharge", [][]byte{}, uint64(0), [][]byte{}, false)
+			if err != nil {
+				t.Fatalf("pack call: %v", err)
+			}
+			tx := types.NewTransaction(0, common.HexToAddress("0x01"), new(big.Int), 0, big.NewInt(1), data)
+
+			err = c.simulate(tx)
+			if len(node.blocks) != 1 || node.blocks[0] != "pending" {
+				t.Fatalf("white list read at %v, want the pending state", node.blocks)
+			}
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("simulate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("simulate error %v, want %q", err, test.wantErr)
+			}
+			if category := errorCategoryOf(err); category != revertError {
+				t.Fatalf("error category %s, want %s", category, revertError)
+			}
+		})
+	}
+}
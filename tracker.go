@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// cancelGasLimit is the gas of the plain transfer which cancels a stuck tx
+const cancelGasLimit = 21000
+
+var (
+	errFeeCeilingReached = errors.New("fee ceiling reached")
+	errTxDeadline        = errors.New("tx not mined before deadline")
+	errTxCancelled       = errors.New("tx cancelled after deadline")
+)
+
+// trackedTx follows a broker tx and the replacements sent with its nonce,
+// the latest one is the last of txs. settled is set once the nonce has been
+// confirmed, released or abandoned.
+type trackedTx struct {
+	txs     []*types.Transaction
+	startAt uint64
+	sentAt  uint64
+	missing int
+	settled bool
+}
+
+func newTrackedTx(tx *types.Transaction) *trackedTx {
+	return &trackedTx{txs: []*types.Transaction{tx}}
+}
+
+func (t *trackedTx) nonce() uint64 {
+	return t.txs[0].Nonce()
+}
+
+func (t *trackedTx) latest() *types.Transaction {
+	return t.txs[len(t.txs)-1]
+}
+
+// checkDropped tells whether the nonce of the missing txs has been used by
+// another tx, otherwise the txs have been dropped and the nonce is reused.
+func (c *Client) checkDropped(t *trackedTx) error {
+	opts := c.transactOpts()
+	nonce, err := c.ethClient().NonceAt(c.ctx, opts.From, nil)
+	if err != nil {
+		return fmt.Errorf("get nonce of %s: %w", opts.From.Hex(), err)
+	}
+
+	hash := t.latest().Hash().Hex()
+	t.settled = true
+	if nonce > t.nonce() {
+		c.nonces.confirm(t.nonce())
+		logger.Warn("Tx replaced", "hash", hash, "nonce", t.nonce())
+		return fmt.Errorf("%w: %s", errTxReplaced, hash)
+	}
+
+	c.nonces.release(t.nonce())
+	logger.Warn("Tx dropped", "hash", hash, "nonce", t.nonce())
+	return fmt.Errorf("%w: %s", errTxDropped, hash)
+}
+
+// settleNonce abandons the nonce of a tx whose tracking ended without
+// confirming or releasing it, so that the nonce manager never waits for a tx
+// nobody tracks
+func (c *Client) settleNonce(t *trackedTx) {
+	if t.settled {
+		return
+	}
+	c.nonces.abandon(t.nonce())
+	t.settled = true
+}
+
+// giveUp stops tracking a tx which may still be pending in mempool
+func (c *Client) giveUp(t *trackedTx, err error) error {
+	c.settleNonce(t)
+	logger.Error("Give up tracking tx", "hash", t.latest().Hash().Hex(), "nonce", t.nonce(), "err", err.Error())
+	return fmt.Errorf("%w: %s", err, t.latest().Hash().Hex())
+}
+
+// minedReceipt returns the receipt of whichever tracked tx has been mined
+func (c *Client) minedReceipt(t *trackedTx) (*types.Receipt, error) {
+	for _, tx := range t.txs {
+		receipt, err := c.ethClient().TransactionReceipt(c.ctx, tx.Hash())
+		if err == nil {
+			return receipt, nil
+		}
+		if err != ethereum.NotFound {
+			return nil, err
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+// inMempool reports whether any tracked tx is still known by a node. A tx
+// may have been sent through an endpoint the client failed over from, and
+// the mempools of the nodes need not agree, so it only counts as missing if
+// no answering endpoint knows it. An error of any of them leaves it
+// undecided.
+func (c *Client) inMempool(t *trackedTx) (bool, error) {
+	for _, eth := range c.answeringClients() {
+		for _, tx := range t.txs {
+			_, _, err := eth.TransactionByHash(c.ctx, tx.Hash())
+			if err == nil {
+				return true, nil
+			}
+			if err != ethereum.NotFound {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// speedUp rebroadcasts the latest tracked tx with bumped fees
+func (c *Client) speedUp(t *trackedTx) error {
+	latest := t.latest()
+	tx, err := c.replaceTx(latest, latest.To(), latest.Value(), latest.Gas(), latest.Data())
+	if err != nil {
+		return err
+	}
+	t.txs = append(t.txs, tx)
+	logger.Warn("Speed up stuck tx", "hash", latest.Hash().Hex(), "replacement", tx.Hash().Hex(), "nonce", tx.Nonce(),
+		"gas price", tx.GasPrice(), "fee cap", tx.GasFeeCap(), "tip cap", tx.GasTipCap())
+	return nil
+}
+
+// cancelTx replaces the latest tracked tx by an empty transfer to the sender
+func (c *Client) cancelTx(t *trackedTx) (*types.Transaction, error) {
+	latest := t.latest()
+	from := c.transactOpts().From
+	tx, err := c.replaceTx(latest, &from, new(big.Int), cancelGasLimit, nil)
+	if err != nil {
+		return nil, err
+	}
+	t.txs = append(t.txs, tx)
+	logger.Warn("Cancel stuck tx", "hash", latest.Hash().Hex(), "replacement", tx.Hash().Hex(), "nonce", tx.Nonce())
+	return tx, nil
+}
+
+// replaceTx signs and sends a tx with the nonce of old and fees bumped by
+// bump_percent, limited by the fee ceilings.
+func (c *Client) replaceTx(old *types.Transaction, to *common.Address, value *big.Int, gas uint64, data []byte) (*types.Transaction, error) {
+	cfg := c.config.Fee
+	var inner types.TxData
+	switch old.Type() {
+	case types.DynamicFeeTxType:
+		feeCap := capFee("fee cap", bumpFee(old.GasFeeCap(), cfg.BumpPercent), cfg.MaxFeeCap)
+		tip := capFee("tip cap", bumpFee(old.GasTipCap(), cfg.BumpPercent), cfg.MaxTipCap)
+		if feeCap.Cmp(old.GasFeeCap()) <= 0 || tip.Cmp(old.GasTipCap()) <= 0 {
+			return nil, errFeeCeilingReached
+		}
+		if tip.Cmp(feeCap) > 0 {
+			tip = feeCap
+		}
+		inner = &types.DynamicFeeTx{
+			ChainID:   old.ChainId(),
+			Nonce:     old.Nonce(),
+			GasTipCap: tip,
+			GasFeeCap: feeCap,
+			Gas:       gas,
+			To:        to,
+			Value:     value,
+			Data:      data,
+		}
+	default:
+		price := capFee("gas price", bumpFee(old.GasPrice(), cfg.BumpPercent), cfg.MaxGasPrice)
+		if price.Cmp(old.GasPrice()) <= 0 {
+			return nil, errFeeCeilingReached
+		}
+		inner = &types.LegacyTx{
+			Nonce:    old.Nonce(),
+			GasPrice: price,
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		}
+	}
+
+	opts := c.transactOpts()
+	tx, err := opts.Signer(opts.From, types.NewTx(inner))
+	if err != nil {
+		return nil, fmt.Errorf("sign replacement tx: %w", err)
+	}
+	if err := c.ethClient().SendTransaction(c.ctx, tx); err != nil {
+		return nil, fmt.Errorf("send replacement tx: %w", err)
+	}
+
+	return tx, nil
+}
+
+func bumpFee(fee *big.Int, percent uint64) *big.Int {
+	bumped := new(big.Int).Mul(fee, new(big.Int).SetUint64(100+percent))
+	bumped.Div(bumped, big.NewInt(100))
+	return bumped.Add(bumped, big.NewInt(1))
+}
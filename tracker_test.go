@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var errNodeDown = errors.New("node down")
+
+// lostTxNode is a node which knows no tx, and the confirmed nonce of which is
+// nonce, or unavailable if nonce is nil
+type lostTxNode struct {
+	nonce *uint64
+}
+
+func (n *lostTxNode) GetTransactionReceipt(common.Hash) (*types.Receipt, error) {
+	return nil, nil
+}
+
+func (n *lostTxNode) GetTransactionByHash(common.Hash) (*types.Transaction, error) {
+	return nil, nil
+}
+
+func (n *lostTxNode) GetTransactionCount(common.Address, string) (hexutil.Uint64, error) {
+	if n.nonce == nil {
+		return 0, errNodeDown
+	}
+	return hexutil.Uint64(*n.nonce), nil
+}
+
+// newLostTxClient returns a client on node whose head moves on quickly, so
+// that a missing tx is found dropped after a few checks
+func newLostTxClient(t *testing.T, node *lostTxNode, from common.Address) *Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("register fake node: %v", err)
+	}
+
+	c := &Client{config: defaultConfig(), nonces: newNonceManager(from)}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	rpcCli := rpc.DialInProc(server)
+	c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+	c.broker = &relayBroker{session: &BrokerSession{TransactOpts: bind.TransactOpts{From: from}}}
+	c.heads = newHeadTracker(c)
+	c.heads.update(100)
+	go func() {
+		for head := uint64(101); ; head++ {
+			select {
+			case <-time.After(10 * time.Millisecond):
+				c.heads.update(head)
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		c.cancel()
+		rpcCli.Close()
+	})
+	return c
+}
+
+func TestWaitForConfirmedSettlesLostTx(t *testing.T) {
+	from := common.HexToAddress("0x01")
+	mined := uint64(6)
+
+	for _, test := range []struct {
+		name    string
+		nonce   *uint64
+		wantErr error
+	}{
+		{name: "dropped", nonce: new(uint64), wantErr: errTxDropped},
+		{name: "replaced", nonce: &mined, wantErr: errTxReplaced},
+		{name: "nonce unavailable", wantErr: errNodeDown},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c := newLostTxClient(t, &lostTxNode{nonce: test.nonce}, from)
+			pending := pendingNonce(5)
+			nonce := acquireNonce(t, c.nonces, &pending)
+			tx := types.NewTransaction(nonce, common.HexToAddress("0x02"), new(big.Int), 21000, big.NewInt(1), nil)
+
+			// errors of the node come back as rpc errors with the same message
+			_, err := c.waitForConfirmed(tx)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr.Error()) {
+				t.Fatalf("waitForConfirmed error %v, want %v", err, test.wantErr)
+			}
+			if c.nonces.inFlight[nonce] {
+				t.Fatalf("nonce %d still in flight", nonce)
+			}
+			pending = 7
+			if n := acquireNonce(t, c.nonces, &pending); n != 7 {
+				t.Fatalf("next nonce %d, want the pending nonce 7", n)
+			}
+		})
+	}
+}
+
+// knownTxNode knows tx as pending
+type knownTxNode struct {
+	tx *types.Transaction
+}
+
+func (n *knownTxNode) GetTransactionByHash(hash common.Hash) (*types.Transaction, error) {
+	if hash == n.tx.Hash() {
+		return n.tx, nil
+	}
+	return nil, nil
+}
+
+// nodeEndpoint returns an answering endpoint connected to an in process node
+// serving node as its eth namespace
+func nodeEndpoint(t *testing.T, addr string, node interface{}) *endpoint {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", node); err != nil {
+		t.Fatalf("register fake node: %v", err)
+	}
+	rpcCli := rpc.DialInProc(server)
+	t.Cleanup(rpcCli.Close)
+	return &endpoint{addr: addr, rpc: rpcCli, eth: ethclient.NewClient(rpcCli), ok: true, probes: []bool{true}}
+}
+
+func TestInMempoolAsksAnsweringEndpoints(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(3, common.HexToAddress("0x02"), new(big.Int), 21000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := nodeEndpoint(t, "active", &lostTxNode{})
+	sender := nodeEndpoint(t, "sender", &knownTxNode{tx: tx})
+	c := &Client{ctx: context.Background(), endpoints: &endpointPool{endpoints: []*endpoint{active, sender}}}
+	c.conn.Store(newNodeConn(active, active.rpc, active.eth))
+
+	pending, err := c.inMempool(newTrackedTx(tx))
+	if err != nil || !pending {
+		t.Fatalf("pending %v, err %v, want the tx known by the endpoint it was sent through", pending, err)
+	}
+
+	// an endpoint which does not answer is not asked
+	sender.ok = false
+	pending, err = c.inMempool(newTrackedTx(tx))
+	if err != nil || pending {
+		t.Fatalf("pending %v, err %v, want missing on the answering endpoints", pending, err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Rican7/retry/strategy"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type errorCategory int
+
+const (
+	transientError errorCategory = iota
+	nonceError
+	fundsError
+	revertError
+	fatalError
+	// acceptedError is a send rejected because the node has the tx already,
+	// typically after a send timed out but reached the node
+	acceptedError
+)
+
+func (c errorCategory) String() string {
+	switch c {
+	case nonceError:
+		return "nonce"
+	case fundsError:
+		return "funds"
+	case revertError:
+		return "revert"
+	case fatalError:
+		return "fatal"
+	case acceptedError:
+		return "accepted"
+	default:
+		return "transient"
+	}
+}
+
+// retryPolicy tells how a failed send is retried, maxAttempts of 0 means
+// retrying until success
+type retryPolicy struct {
+	wait        time.Duration
+	maxAttempts uint
+}
+
+var retryPolicies = map[errorCategory]retryPolicy{
+	transientError: {wait: 2 * time.Second},
+	nonceError:     {wait: time.Second, maxAttempts: 5},
+	fundsError:     {wait: 30 * time.Second, maxAttempts: 10},
+	revertError:    {maxAttempts: 1},
+	fatalError:     {maxAttempts: 1},
+}
+
+// errorPatterns are the messages of geth, erigon, besu and openethereum
+// for each category, matched in lower case
+var errorPatterns = []struct {
+	category errorCategory
+	patterns []string
+}{
+	{revertError, []string{
+		"execution reverted",
+		"vm execution error",
+		"reverted",
+		"invalid opcode",
+	}},
+	{nonceError, []string{
+		"nonce too low",
+		"nonce too high",
+		"nonce_too_low",
+		"nonce_too_high",
+		"nonce is too low",
+		"replacement transaction underpriced",
+		"replacement_underpriced",
+		"tx_replacement_underpriced",
+	}},
+	{acceptedError, []string{
+		"already known",
+		"known transaction",
+		"already imported",
+		"transaction with the same hash was already imported",
+	}},
+	{fundsError, []string{
+		"insufficient funds",
+		"insufficient balance",
+		"upfront_cost_exceeds_balance",
+		"sender doesn't have enough funds",
+	}},
+	{fatalError, []string{
+		"invalid sender",
+		"invalid signature",
+		"invalid_signature",
+		"invalid chain id",
+		"wrong_chain_id",
+		"only replay-protected",
+		"intrinsic gas too low",
+		"exceeds block gas limit",
+		"exceeds_block_gas_limit",
+		"tx fee",
+		"gas limit reached",
+		"method not found",
+		"invalid argument",
+		"context canceled",
+		"context deadline exceeded",
+	}},
+}
+
+// classifyError maps a JSON-RPC error to its category by code and message,
+// the unknown errors are regarded as transient. A cancelled or timed out
+// context fails every retry the same way, so it is fatal.
+func classifyError(err error) errorCategory {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return fatalError
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		switch rpcErr.ErrorCode() {
+		case 3:
+			return revertError
+		case -32601, -32602:
+			return fatalError
+		case -32005:
+			return transientError
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, group := range errorPatterns {
+		for _, pattern := range group.patterns {
+			if strings.Contains(msg, pattern) {
+				return group.category
+			}
+		}
+	}
+
+	return transientError
+}
+
+// txError is a failed send of a broker transaction with its category
+type txError struct {
+	category errorCategory
+	err      error
+}
+
+func (e *txError) Error() string {
+	return e.err.Error()
+}
+
+func (e *txError) Unwrap() error {
+	return e.err
+}
+
+func newTxError(err error) *txError {
+	return &txError{category: classifyError(err), err: err}
+}
+
+func errorCategoryOf(err error) errorCategory {
+	var txErr *txError
+	if errors.As(err, &txErr) {
+		return txErr.category
+	}
+	return classifyError(err)
+}
+
+// giveUpSend reports whether a failed send should be returned to the caller
+// according to the retry policy of its category
+func giveUpSend(err error, attempt uint) bool {
+	category := errorCategoryOf(err)
+	policy := retryPolicies[category]
+	if policy.maxAttempts != 0 && attempt+1 >= policy.maxAttempts {
+		logger.Warn("Give up sending tx", "category", category.String(), "attempts", attempt+1, "error", err.Error())
+		return true
+	}
+	return false
+}
+
+// sendRetryWait waits between sends according to the category of the last
+// send error
+func sendRetryWait(lastErr *error) strategy.Strategy {
+	return func(attempt uint) bool {
+		if attempt != 0 && *lastErr != nil {
+			time.Sleep(retryPolicies[errorCategoryOf(*lastErr)].wait)
+		}
+		return true
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// codeError is a JSON-RPC error with code
+type codeError struct {
+	code int
+	msg  string
+}
+
+func (e *codeError) Error() string  { return e.msg }
+func (e *codeError) ErrorCode() int { return e.code }
+
+func TestClassifyError(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+		want errorCategory
+	}{
+		{name: "cancelled", err: context.Canceled, want: fatalError},
+		{name: "wrapped deadline", err: fmt.Errorf("send tx: %w", context.DeadlineExceeded), want: fatalError},
+		{name: "deadline by message", err: errors.New("post: context deadline exceeded"), want: fatalError},
+		{name: "revert code", err: &codeError{code: 3, msg: "execution reverted: not whitelisted"}, want: revertError},
+		{name: "nonce", err: errors.New("nonce too low"), want: nonceError},
+		{name: "already known", err: &codeError{code: -32000, msg: "already known"}, want: acceptedError},
+		{name: "known transaction", err: errors.New("known transaction: 8ba1f109551bd432803012645ac136ddd64dba72"), want: acceptedError},
+		{name: "already imported", err: errors.New("Transaction with the same hash was already imported."), want: acceptedError},
+		{name: "replacement underpriced", err: errors.New("replacement transaction underpriced"), want: nonceError},
+		{name: "funds", err: &codeError{code: -32000, msg: "insufficient funds for gas * price + value"}, want: fundsError},
+		{name: "rate limited", err: &codeError{code: -32005, msg: "limit exceeded"}, want: transientError},
+		{name: "unknown", err: errors.New("connection reset by peer"), want: transientError},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := classifyError(test.err); got != test.want {
+				t.Fatalf("category %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGiveUpSendOnCancel(t *testing.T) {
+	if !giveUpSend(newTxError(context.Canceled), 0) {
+		t.Fatal("send retried after the context was cancelled")
+	}
+	if giveUpSend(newTxError(errors.New("connection reset by peer")), 3) {
+		t.Fatal("gave up a transient error")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// headerWindow is how many emitted headers are remembered to find where a
+// reorg forked off
+const headerWindow = 256
+
+// headerStream emits the confirmed headers of the canonical chain as update
+// metas. Every header is emitted once and in order, unless a reorg below the
+// confirmed height replaces it, then the new branch is emitted again from the
+// fork point.
+type headerStream struct {
+	next    uint64
+	emitted map[uint64]common.Hash
+}
+
+func (s *headerStream) record(header *types.Header) {
+	height := header.Number.Uint64()
+	s.emitted[height] = header.Hash()
+	if height >= headerWindow {
+		delete(s.emitted, height-headerWindow)
+	}
+	s.next = height + 1
+}
+
+// GetUpdateMeta returns the stream of confirmed block headers. The meta of an
+// update is the RLP encoded header, which the receipt proofs of the IBTPs
+// can be verified against. The headers are streamed once pier asks for them,
+// so a pier which never reads them does not stall on a full channel.
+func (c *Client) GetUpdateMeta() chan *pb.UpdateMeta {
+	c.streamOnce.Do(func() {
+		go c.streamHeaders()
+	})
+	return c.updateMeta
+}
+
+// newHeaderStream resumes after the last emitted header, or starts at the
+// confirmed head
+func (c *Client) newHeaderStream() (*headerStream, error) {
+	s := &headerStream{emitted: make(map[uint64]common.Hash)}
+
+	cp, err := c.checkpoint.LoadHeader()
+	if err != nil {
+		return nil, err
+	}
+	if cp != nil {
+		s.emitted[cp.Height] = cp.Hash
+		s.next = cp.Height + 1
+		return s, nil
+	}
+
+	head, err := c.getBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	if s.next, err = c.confirmedHeight(head); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *Client) streamHeaders() {
+	heads, unsubscribe := c.heads.subscribe()
+	defer unsubscribe()
+
+	var s *headerStream
+	ticker := time.NewTicker(headStaleAfter)
+	defer ticker.Stop()
+	for {
+		var err error
+		if s == nil {
+			s, err = c.newHeaderStream()
+		}
+		if err == nil {
+			err = c.emitHeaders(s)
+		}
+		if err != nil {
+			logger.Warn("Emit block headers", "err", err.Error())
+		}
+
+		select {
+		case <-heads:
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// emitHeaders emits the headers up to the confirmed height
+func (c *Client) emitHeaders(s *headerStream) error {
+	head, err := c.heads.current()
+	if err != nil {
+		return err
+	}
+	confirmed, err := c.confirmedHeight(head)
+	if err != nil {
+		return err
+	}
+	if _, err := c.rewindHeaders(s); err != nil {
+		return err
+	}
+
+	for s.next <= confirmed {
+		header, err := c.ethClient().HeaderByNumber(c.ctx, new(big.Int).SetUint64(s.next))
+		if err != nil {
+			return fmt.Errorf("get header %d: %w", s.next, err)
+		}
+		if parent, ok := s.emitted[s.next-1]; ok && s.next != 0 && header.ParentHash != parent {
+			rewound, err := c.rewindHeaders(s)
+			if err != nil {
+				return err
+			}
+			if !rewound {
+				return fmt.Errorf("header %d does not extend the emitted header %s", s.next, parent.Hex())
+			}
+			continue
+		}
+
+		data, err := rlp.EncodeToBytes(header)
+		if err != nil {
+			return err
+		}
+		select {
+		case c.updateMeta <- &pb.UpdateMeta{Meta: data}:
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+		s.record(header)
+		if err := c.checkpoint.SaveHeader(header.Number.Uint64(), header.Hash()); err != nil {
+			logger.Warn("save header checkpoint", "height", header.Number, "err", err.Error())
+		}
+	}
+	return nil
+}
+
+// rewindHeaders moves the stream back to the highest emitted header which is
+// still canonical, so the headers of the new branch are emitted after it. It
+// reports whether the stream has been moved back.
+func (c *Client) rewindHeaders(s *headerStream) (bool, error) {
+	if s.next == 0 {
+		return false, nil
+	}
+
+	last := s.next - 1
+	height := last
+	for {
+		hash, ok := s.emitted[height]
+		if !ok {
+			// forked below the remembered headers, emit from the oldest one
+			height++
+			break
+		}
+		canonical, err := c.canonicalHash(height)
+		if err != nil {
+			return false, err
+		}
+		if canonical == hash {
+			height++
+			break
+		}
+		delete(s.emitted, height)
+		if height == 0 {
+			break
+		}
+		height--
+	}
+
+	if height == last+1 {
+		return false, nil
+	}
+	logger.Warn("Chain reorganized, emit the canonical headers again", "from", height, "emitted to", last)
+	s.next = height
+	return true, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/meshplus/bitxhub-model/pb"
+)
+
+// chainNode serves a linear chain of headers up to head
+type chainNode struct {
+	head    uint64
+	headers []*types.Header
+}
+
+func newChainNode(head uint64) *chainNode {
+	n := &chainNode{head: head}
+	for height := uint64(0); height <= head; height++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(height), Difficulty: new(big.Int), Extra: []byte{}}
+		if height != 0 {
+			header.ParentHash = n.headers[height-1].Hash()
+		}
+		n.headers = append(n.headers, header)
+	}
+	return n
+}
+
+func (n *chainNode) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(n.head)
+}
+
+func (n *chainNode) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	height, err := strconv.ParseUint(number, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported block %s", number)
+	}
+	if height > n.head {
+		return nil, nil
+	}
+	return n.headers[height], nil
+}
+
+func TestGetUpdateMetaStartsStream(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", newChainNode(5)); err != nil {
+		t.Fatalf("register fake node: %v", err)
+	}
+	rpcCli := rpc.DialInProc(server)
+	defer rpcCli.Close()
+
+	store, err := NewCheckpointStore(t.TempDir(), "0x01")
+	if err != nil {
+		t.Fatalf("open checkpoint store: %v", err)
+	}
+	defer store.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &Client{ctx: ctx, checkpoint: store, updateMeta: make(chan *pb.UpdateMeta, 1), confirm: confirmPolicy{depth: 2}}
+	c.heads = newHeadTracker(c)
+	c.conn.Store(newNodeConn(&endpoint{addr: "inproc"}, rpcCli, ethclient.NewClient(rpcCli)))
+
+	select {
+	case <-c.updateMeta:
+		t.Fatal("headers streamed before pier asked for them")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// asking again must not start a second stream emitting the same headers
+	metas := c.GetUpdateMeta()
+	c.GetUpdateMeta()
+	select {
+	case meta := <-metas:
+		var header types.Header
+		if err := rlp.DecodeBytes(meta.Meta, &header); err != nil {
+			t.Fatalf("decode header: %v", err)
+		}
+		if header.Number.Uint64() != 3 {
+			t.Fatalf("header %d streamed, want the confirmed header 3", header.Number)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("confirmed header not streamed")
+	}
+	select {
+	case meta := <-metas:
+		t.Fatalf("unexpected meta %x past the confirmed height", meta.Meta)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
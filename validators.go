@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/viper"
+)
+
+// expectedValidators is the validator set pier is configured with
+type expectedValidators struct {
+	validators []common.Address
+	// threshold is 0 when none is configured
+	threshold uint64
+	source    string
+}
+
+// loadExpectedValidators reads the validator set pier is configured with, a
+// relative path is resolved against the plugin config directory
+func loadExpectedValidators(configPath string, cfg Validators) (*expectedValidators, error) {
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(configPath, path)
+	}
+
+	var expected *expectedValidators
+	switch {
+	case cfg.PierConfig != "":
+		path := resolve(cfg.PierConfig)
+		v := viper.New()
+		v.SetConfigFile(path)
+		v.SetConfigType("toml")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("read pier config %s: %w", path, err)
+		}
+		validators, err := parseAddresses(v.GetStringSlice("mode.relay.validators"))
+		if err != nil {
+			return nil, fmt.Errorf("validators of %s: %w", path, err)
+		}
+		expected = &expectedValidators{
+			validators: validators,
+			threshold:  v.GetUint64("mode.relay.quorum"),
+			source:     path,
+		}
+	case cfg.Path != "":
+		path := resolve(cfg.Path)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read validators %s: %w", path, err)
+		}
+		validators, err := parseAddresses(strings.Split(string(data), ","))
+		if err != nil {
+			return nil, fmt.Errorf("validators of %s: %w", path, err)
+		}
+		expected = &expectedValidators{validators: validators, source: path}
+	default:
+		return nil, fmt.Errorf("no validators path or pier config configured")
+	}
+
+	if cfg.Threshold != 0 {
+		expected.threshold = cfg.Threshold
+	}
+	return expected, nil
+}
+
+func parseAddresses(addrs []string) ([]common.Address, error) {
+	var ret []common.Address
+	for _, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid address %q", addr)
+		}
+		ret = append(ret, common.HexToAddress(addr))
+	}
+	return ret, nil
+}
+
+// validatorDrift is how the validator set of the broker differs from the
+// expected one
+type validatorDrift struct {
+	// Missing are expected but not on chain, Unexpected are on chain only
+	Missing           []common.Address
+	Unexpected        []common.Address
+	Threshold         uint64
+	ExpectedThreshold uint64
+}
+
+func diffValidators(onchain []common.Address, threshold uint64, expected *expectedValidators) *validatorDrift {
+	drift := &validatorDrift{Threshold: threshold, ExpectedThreshold: expected.threshold}
+
+	onchainSet := make(map[common.Address]bool, len(onchain))
+	for _, addr := range onchain {
+		onchainSet[addr] = true
+	}
+	expectedSet := make(map[common.Address]bool, len(expected.validators))
+	for _, addr := range expected.validators {
+		expectedSet[addr] = true
+		if !onchainSet[addr] {
+			drift.Missing = append(drift.Missing, addr)
+		}
+	}
+	for _, addr := range onchain {
+		if !expectedSet[addr] {
+			drift.Unexpected = append(drift.Unexpected, addr)
+		}
+	}
+	return drift
+}
+
+// empty reports whether the sets agree, the threshold only counts if one is
+// expected
+func (d *validatorDrift) empty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0 &&
+		(d.ExpectedThreshold == 0 || d.ExpectedThreshold == d.Threshold)
+}
+
+func (d *validatorDrift) String() string {
+	if d.empty() {
+		return "no drift"
+	}
+	var parts []string
+	if len(d.Missing) != 0 {
+		parts = append(parts, fmt.Sprintf("missing on chain: %s", joinAddresses(d.Missing)))
+	}
+	if len(d.Unexpected) != 0 {
+		parts = append(parts, fmt.Sprintf("unexpected on chain: %s", joinAddresses(d.Unexpected)))
+	}
+	if d.ExpectedThreshold != 0 && d.ExpectedThreshold != d.Threshold {
+		parts = append(parts, fmt.Sprintf("threshold %d on chain, %d expected", d.Threshold, d.ExpectedThreshold))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func joinAddresses(addrs []common.Address) string {
+	hexes := make([]string, len(addrs))
+	for i, addr := range addrs {
+		hexes[i] = addr.Hex()
+	}
+	return strings.Join(hexes, ",")
+}
+
+// checkValidators compares the validator set of the broker with the one pier
+// is configured with
+func (c *Client) checkValidators() (*validatorDrift, error) {
+	expected, err := loadExpectedValidators(c.configPath, c.config.Validators)
+	if err != nil {
+		return nil, err
+	}
+	validators, threshold, err := c.loadValidators(true)
+	if err != nil {
+		return nil, err
+	}
+	return diffValidators(validators, threshold, expected), nil
+}
+
+// watchValidators reports every check_interval whether the validator set of
+// the broker has drifted from the one pier is configured with. A drift
+// breaks the multi-signature checks until the set is synchronized with the
+// validators sync command.
+func (c *Client) watchValidators() {
+	if _, ok := c.broker.(validatorSet); !ok || c.config.Validators.CheckInterval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(c.config.Validators.CheckInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		drift, err := c.checkValidators()
+		switch {
+		case err != nil:
+			logger.Warn("Check validator set", "err", err.Error())
+		case !drift.empty():
+			logger.Warn("Validator set drifted, sync it with the validators sync command",
+				"missing", joinAddresses(drift.Missing),
+				"unexpected", joinAddresses(drift.Unexpected),
+				"threshold", drift.Threshold,
+				"expected threshold", drift.ExpectedThreshold)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}